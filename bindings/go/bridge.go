@@ -6,7 +6,7 @@ import (
 	"C"
 	"fmt"
 
-	"github.com/redgiant/rgtp-go"
+	rgtp "github.com/rawscript/red-giant/bindings/go"
 )
 
 //export RgtpInitialize
@@ -0,0 +1,262 @@
+package rgtp
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ── Directory transfer ───────────────────────────────────────────────────
+
+// SendDirectory packs the tree rooted at dir into a tar stream and exposes
+// it as a single Exposure. The returned Surface must be polled to serve
+// pull requests, exactly as for Expose.
+//
+// Empty directories are preserved. Symlinks are sent as links, not
+// followed; a link that is absolute, points outside dir, or has ".."
+// after a normal path component is an error.
+// Any other non-regular file (device, socket, pipe) is also an error.
+//
+// Like Expose, the exposer serves from memory: the whole tar stream is
+// built in memory first, so the tree must fit there.
+func SendDirectory(ctx context.Context, sock *Socket, dir string) (*Surface, error) {
+	data, err := packDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Expose(ctx, sock, data)
+}
+
+// ReceiveDirectory pulls an Exposure created by SendDirectory and unpacks
+// it under destDir, preserving relative paths. The returned Stats cover
+// the whole tree, since it travels as one Exposure.
+//
+// The tar stream is staged in a temporary file under os.TempDir, as
+// PullToFile would write it, so memory use does not grow with the tree.
+// Nothing is unpacked until every chunk has arrived and verified, so
+// opts.AllowPartial is ignored; opts.Key and opts.MaxBytes apply as for
+// PullToFile.
+func ReceiveDirectory(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, destDir string, opts *PullOptions) (Stats, error) {
+
+	if opts != nil && opts.AllowPartial {
+		o := *opts
+		o.AllowPartial = false
+		opts = &o
+	}
+	tmp, err := os.MkdirTemp("", "rgtp-dir-")
+	if err != nil {
+		return Stats{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	archive := filepath.Join(tmp, "tree.tar")
+	stats, err := pullToFile(ctx, sock, server, exposureID, archive, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer f.Close()
+	if err := unpackDirectory(f, destDir); err != nil {
+		return Stats{}, err
+	}
+	return stats, nil
+}
+
+// checkLink rejects symlink targets that could resolve outside the tree.
+// name is the slash-separated path of the link itself within the tree.
+//
+// Beyond the textual check, ".." may only lead the target: after a
+// component that may itself be a symlink, ".." climbs from wherever that
+// link points rather than from its textual parent.
+func checkLink(name, target string) error {
+	escapes := filepath.IsAbs(target) ||
+		!filepath.IsLocal(filepath.Join(filepath.Dir(filepath.FromSlash(name)), target))
+	descended := false
+	for _, c := range strings.Split(filepath.ToSlash(target), "/") {
+		switch c {
+		case "..":
+			escapes = escapes || descended
+		case "", ".":
+		default:
+			descended = true
+		}
+	}
+	if escapes {
+		return fmt.Errorf("rgtp: symlink %s -> %s escapes the directory", name, target)
+	}
+	return nil
+}
+
+// packDirectory serialises the tree rooted at dir as a tar stream.
+func packDirectory(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+			if err := checkLink(filepath.ToSlash(rel), link); err != nil {
+				return err
+			}
+		case d.IsDir(), d.Type().IsRegular():
+		default:
+			return fmt.Errorf("rgtp: %s: unsupported file type %s", rel, d.Type())
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unpackDirectory extracts a tar stream produced by packDirectory under
+// destDir. The stream comes from the remote exposer and is untrusted:
+// entries that would land outside destDir are rejected, and so is any
+// entry whose parent is a symlink, since a link pointing back into the
+// tree would still shift where checkLink's relative targets resolve.
+// Directories and files are created through an os.Root on destDir.
+func unpackDirectory(r io.Reader, destDir string) error {
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("rgtp: entry %q escapes the destination", hdr.Name)
+		}
+		name = filepath.Clean(name)
+		if err := mkdirAll(root, filepath.Dir(name), 0o755); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirAll(root, name, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLink(hdr.Name, hdr.Linkname); err != nil {
+				return err
+			}
+			// os.Root gains Symlink only in Go 1.25; the parent was just
+			// verified to be a real directory inside destDir.
+			if err := os.Symlink(hdr.Linkname, filepath.Join(destDir, name)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			f, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
+				hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("rgtp: entry %q has unsupported type %q", hdr.Name, hdr.Typeflag)
+		}
+	}
+}
+
+// mkdirAll creates dir and any missing parents under root, like
+// os.MkdirAll. Every existing component must be a real directory; a
+// symlink anywhere on the path is an error.
+func mkdirAll(root *os.Root, dir string, perm fs.FileMode) error {
+	if dir == "." {
+		return nil
+	}
+	path := ""
+	for _, c := range strings.Split(dir, string(filepath.Separator)) {
+		path = filepath.Join(path, c)
+		fi, err := root.Lstat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if err := root.Mkdir(path, perm); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case fi.Mode()&fs.ModeSymlink != 0:
+			return fmt.Errorf("rgtp: entry path %s passes through a symlink", filepath.ToSlash(path))
+		case !fi.IsDir():
+			return fmt.Errorf("rgtp: entry path %s is not a directory", filepath.ToSlash(path))
+		}
+	}
+	return nil
+}
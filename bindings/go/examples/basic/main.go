@@ -0,0 +1,118 @@
+// Command basic exposes a file over RGTP or pulls one from a remote
+// exposer, using the public rgtp binding API.
+//
+//	basic -port 9000 expose <file>
+//	basic pull <host:port> <exposure-id-hex> <key-hex> <output-file>
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+
+	rgtp "github.com/rawscript/red-giant/bindings/go"
+)
+
+var port = flag.Uint("port", 0, "UDP port to bind (0 = auto-assign)")
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 2 {
+		log.Fatal("Usage: basic [-port N] expose <file> | basic pull <host:port> <exposure-id-hex> <key-hex> <output-file>")
+	}
+
+	if err := rgtp.Init(); err != nil {
+		log.Fatalf("Failed to initialize RGTP: %v", err)
+	}
+	defer rgtp.Cleanup()
+	fmt.Printf("RGTP Version: %s\n", rgtp.Version())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	sock, err := rgtp.NewSocketWithConfig(&rgtp.Config{Port: uint16(*port)})
+	if err != nil {
+		log.Fatalf("Failed to create socket: %v", err)
+	}
+	defer sock.Close()
+
+	switch args[0] {
+	case "expose":
+		expose(ctx, sock, args[1])
+	case "pull":
+		if len(args) != 5 {
+			log.Fatal("Usage: basic pull <host:port> <exposure-id-hex> <key-hex> <output-file>")
+		}
+		pull(ctx, sock, args[1], args[2], args[3], args[4])
+	default:
+		log.Fatalf("Unknown command %q", args[0])
+	}
+}
+
+func expose(ctx context.Context, sock *rgtp.Socket, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	surface, err := rgtp.Expose(ctx, sock, data)
+	if err != nil {
+		log.Fatalf("Expose failed: %v", err)
+	}
+	defer surface.Close()
+
+	id, err := surface.ExposureID()
+	if err != nil {
+		log.Fatal(err)
+	}
+	key, err := surface.Key()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Exposing %s (%d bytes), Exposure ID %x\n", path, len(data), id)
+	fmt.Printf("Key %x (share it with pullers out of band)\n", key)
+	fmt.Println("Press Ctrl-C to stop")
+
+	_ = rgtp.Serve(ctx, surface, rgtp.ServeOptions{})
+	if stats, err := surface.Stats(); err == nil {
+		printStats(stats)
+	}
+}
+
+func pull(ctx context.Context, sock *rgtp.Socket, server, idHex, keyHex, out string) {
+	addr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		log.Fatal(err)
+	}
+	raw, err := hex.DecodeString(idHex)
+	if err != nil || len(raw) != 16 {
+		log.Fatalf("Exposure ID must be 32 hex digits")
+	}
+	var id [16]byte
+	copy(id[:], raw)
+	raw, err = hex.DecodeString(keyHex)
+	if err != nil || len(raw) != 32 {
+		log.Fatalf("Key must be 64 hex digits")
+	}
+	opts := &rgtp.PullOptions{}
+	copy(opts.Key[:], raw)
+
+	if err := rgtp.PullToFile(ctx, sock, addr, id, out, opts); err != nil {
+		log.Fatalf("Pull failed: %v", err)
+	}
+	fmt.Printf("Pulled Exposure %x into %s\n", id, out)
+}
+
+func printStats(stats rgtp.Stats) {
+	fmt.Printf("  Bytes Sent: %d\n", stats.BytesSent)
+	fmt.Printf("  Chunks Sent: %d\n", stats.ChunksSent)
+	fmt.Printf("  Packet Loss Rate: %.4f\n", stats.PacketLossRate)
+	fmt.Printf("  RTT: %d us\n", stats.RTTUs)
+	fmt.Printf("  Average Throughput: %.2f Mbps\n", stats.ThroughputMbps)
+}
@@ -0,0 +1,3 @@
+module github.com/rawscript/red-giant/bindings/go
+
+go 1.24
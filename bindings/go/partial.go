@@ -0,0 +1,221 @@
+package rgtp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ── Partial files ────────────────────────────────────────────────────────
+
+// StateSuffix is appended to the partial file's path to name the sidecar
+// that records which chunks the partial file holds.
+const StateSuffix = ".state"
+
+// ErrPartialMismatch is returned, wrapped, when ResumePull finds a
+// partial file left by a pull of a different Exposure.
+var ErrPartialMismatch = errors.New("rgtp: partial file belongs to another exposure")
+
+// stateMagic opens every sidecar; the version digit changes with the
+// layout below.
+var stateMagic = [8]byte{'R', 'G', 'T', 'P', 'P', 'R', 'T', '1'}
+
+// stateHeaderSize is the sidecar header: magic, Exposure_ID, total size,
+// chunk count and chunk size, big-endian. A bitmap of held chunks, bit
+// idx%8 of byte idx/8, follows it.
+const stateHeaderSize = 8 + 16 + 8 + 4 + 4
+
+// checkpointEvery is how many chunks PullToFile writes between syncing
+// the partial file and recording them in the sidecar.
+const checkpointEvery = 4096
+
+// partialFile is the on-disk state of a file pull in progress: the data
+// file, zero-filled where chunks are missing, and its sidecar.
+//
+// The sidecar only ever claims chunks that were synced to the data file
+// first. Bits are never cleared, so a torn sidecar write can lose recent
+// chunks but never claim one that is not on disk.
+type partialFile struct {
+	path  string // final destination
+	data  *os.File
+	state *os.File
+	held  []bool // chunks written to data, synced or not
+	dirty int    // chunks written since the last checkpoint
+}
+
+func (p *partialFile) dataPath() string  { return p.path + PartialSuffix }
+func (p *partialFile) statePath() string { return p.path + PartialSuffix + StateSuffix }
+
+// createPartial starts a fresh partial file for an Exposure, replacing
+// any left by an earlier pull.
+func createPartial(path string, id [16]byte, layout Layout) (*partialFile, error) {
+	p := &partialFile{path: path, held: make([]bool, layout.ChunkCount)}
+	var err error
+	if p.data, err = os.Create(p.dataPath()); err != nil {
+		return nil, err
+	}
+	if p.state, err = os.Create(p.statePath()); err != nil {
+		p.remove()
+		return nil, err
+	}
+	// Truncate first so gaps read back as zeros
+	if err = p.data.Truncate(int64(layout.TotalSize)); err != nil {
+		p.remove()
+		return nil, err
+	}
+	if _, err = p.state.WriteAt(encodeState(id, layout, p.held), 0); err != nil {
+		p.remove()
+		return nil, err
+	}
+	return p, nil
+}
+
+// openPartial reopens the partial file an earlier pull of the Exposure
+// left at path. It returns nil and no error if there is none.
+func openPartial(path string, id [16]byte, layout Layout) (*partialFile, error) {
+	p := &partialFile{path: path}
+	var err error
+	if p.state, err = os.OpenFile(p.statePath(), os.O_RDWR, 0); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if p.data, err = os.OpenFile(p.dataPath(), os.O_RDWR, 0); err != nil {
+		p.state.Close()
+		return nil, err
+	}
+	if err = p.load(id, layout); err != nil {
+		p.data.Close()
+		p.state.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// load reads the sidecar and checks that it, and the data file, match
+// the Exposure.
+func (p *partialFile) load(id [16]byte, layout Layout) error {
+	want := encodeState(id, layout, nil)
+	buf, err := io.ReadAll(p.state)
+	if err != nil {
+		return err
+	}
+	if len(buf) != len(want)+bitmapLen(layout.ChunkCount) ||
+		!bytes.Equal(buf[:stateHeaderSize], want) {
+		return fmt.Errorf("%w: %s", ErrPartialMismatch, p.statePath())
+	}
+	fi, err := p.data.Stat()
+	if err != nil {
+		return err
+	}
+	if uint64(fi.Size()) != layout.TotalSize {
+		return fmt.Errorf("%w: %s is %d bytes, want %d",
+			ErrPartialMismatch, p.dataPath(), fi.Size(), layout.TotalSize)
+	}
+	bitmap := buf[stateHeaderSize:]
+	p.held = make([]bool, layout.ChunkCount)
+	for idx := range p.held {
+		p.held[idx] = bitmap[idx/8]&(1<<(idx%8)) != 0
+	}
+	return nil
+}
+
+// encodeState returns the sidecar contents for held, or only its header
+// when held is nil.
+func encodeState(id [16]byte, layout Layout, held []bool) []byte {
+	buf := make([]byte, stateHeaderSize, stateHeaderSize+bitmapLen(uint32(len(held))))
+	copy(buf, stateMagic[:])
+	copy(buf[8:], id[:])
+	binary.BigEndian.PutUint64(buf[24:], layout.TotalSize)
+	binary.BigEndian.PutUint32(buf[32:], layout.ChunkCount)
+	binary.BigEndian.PutUint32(buf[36:], layout.ChunkSize)
+	if held == nil {
+		return buf
+	}
+	bitmap := make([]byte, bitmapLen(uint32(len(held))))
+	for idx, ok := range held {
+		if ok {
+			bitmap[idx/8] |= 1 << (idx % 8)
+		}
+	}
+	return append(buf, bitmap...)
+}
+
+func bitmapLen(chunkCount uint32) int {
+	return int((chunkCount + 7) / 8)
+}
+
+// write stores one chunk at its offset in the data file, checkpointing
+// every checkpointEvery chunks.
+func (p *partialFile) write(layout Layout, idx uint32, data []byte) error {
+	end := uint64(idx)*uint64(layout.ChunkSize) + uint64(len(data))
+	if idx >= layout.ChunkCount || len(data) > int(layout.ChunkSize) || end > layout.TotalSize {
+		return fmt.Errorf("rgtp: chunk %d (%d bytes) overruns the layout", idx, len(data))
+	}
+	if _, err := p.data.WriteAt(data, int64(idx)*int64(layout.ChunkSize)); err != nil {
+		return err
+	}
+	p.held[idx] = true
+	if p.dirty++; p.dirty >= checkpointEvery {
+		return p.checkpoint()
+	}
+	return nil
+}
+
+// checkpoint syncs the data file, then records its chunks in the sidecar.
+func (p *partialFile) checkpoint() error {
+	if err := p.data.Sync(); err != nil {
+		return err
+	}
+	bitmap := encodeState([16]byte{}, Layout{}, p.held)[stateHeaderSize:]
+	if _, err := p.state.WriteAt(bitmap, stateHeaderSize); err != nil {
+		return err
+	}
+	p.dirty = 0
+	return p.state.Sync()
+}
+
+// keep checkpoints and closes the partial file so a later ResumePull can
+// pick it up.
+func (p *partialFile) keep() error {
+	err := p.checkpoint()
+	if cerr := p.data.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := p.state.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// commit syncs the complete data file, renames it to the destination and
+// drops the sidecar.
+func (p *partialFile) commit() error {
+	if err := p.data.Sync(); err != nil {
+		return err
+	}
+	if err := p.data.Close(); err != nil {
+		return err
+	}
+	p.state.Close()
+	if err := os.Rename(p.dataPath(), p.path); err != nil {
+		return err
+	}
+	return os.Remove(p.statePath())
+}
+
+// remove closes and deletes the data file and its sidecar.
+func (p *partialFile) remove() {
+	if p.data != nil {
+		p.data.Close()
+	}
+	if p.state != nil {
+		p.state.Close()
+	}
+	os.Remove(p.dataPath())
+	os.Remove(p.statePath())
+}
@@ -1,8 +1,17 @@
 // Package rgtp provides Go bindings for the Red Giant Transport Protocol.
 //
+//	import rgtp "github.com/rawscript/red-giant/bindings/go"
+//
+// The exposer side creates an Exposure with Expose (or SendDirectory) and
+// serves it with Poll or Serve. The puller side fetches it with Pull,
+// PullToFile or ReceiveDirectory, or chunk by chunk with PullStart and
+// PullNext/PullNextInto or the Chunks iterator. Sockets are configured
+// through Config; results are reported through Stats, Layout and the
+// *Error code constants. No C types appear in the exported API.
+//
 // All blocking operations accept a context.Context for cancellation.
-// C memory is managed by the library; Go buffers are pinned for the
-// duration of each call using runtime.Pinner.
+// C memory is managed by the library; Go buffers are passed directly to
+// C and stay pinned for the duration of each call.
 //
 // Requirements: 14.4, 14.5, 14.8, 23.5
 package rgtp
@@ -14,6 +23,22 @@ package rgtp
 #include "rgtp/rgtp.h"
 #include <stdlib.h>
 #include <string.h>
+
+// rgtp_go_pull_next returns rgtp_pull_next's results by value, so Go
+// passes no pointers to its own stack and nothing escapes to the heap.
+typedef struct {
+    rgtp_error_t err;
+    size_t       received;
+    uint32_t     chunk_index;
+} rgtp_go_pull_result;
+
+static rgtp_go_pull_result rgtp_go_pull_next(rgtp_surface_t* surface,
+                                             void* buffer, size_t buf_size)
+{
+    rgtp_go_pull_result r = {RGTP_OK, 0, 0};
+    r.err = rgtp_pull_next(surface, buffer, buf_size, &r.received, &r.chunk_index);
+    return r;
+}
 */
 import "C"
 
@@ -23,6 +48,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"time"
 	"unsafe"
 )
 
@@ -38,6 +64,36 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("rgtp error %d: %s", e.Code, e.Message)
 }
 
+// Error codes carried in Error.Code. Values mirror rgtp_error_t.
+const (
+	ErrCodeNoMem         = int(C.RGTP_ERR_NOMEM)
+	ErrCodeInvalidArg    = int(C.RGTP_ERR_INVALID_ARG)
+	ErrCodeSocket        = int(C.RGTP_ERR_SOCKET)
+	ErrCodeCryptoInit    = int(C.RGTP_ERR_CRYPTO_INIT)
+	ErrCodeEncrypt       = int(C.RGTP_ERR_ENCRYPT)
+	ErrCodeDecrypt       = int(C.RGTP_ERR_DECRYPT)
+	ErrCodeAuthFail      = int(C.RGTP_ERR_AUTH_FAIL)
+	ErrCodeMerkleFail    = int(C.RGTP_ERR_MERKLE_FAIL)
+	ErrCodeFECFail       = int(C.RGTP_ERR_FEC_FAIL)
+	ErrCodeTruncated     = int(C.RGTP_ERR_TRUNCATED)
+	ErrCodeChunkIndexOOB = int(C.RGTP_ERR_CHUNK_INDEX_OOB)
+	ErrCodeTimeout       = int(C.RGTP_ERR_TIMEOUT)
+	ErrCodeRateLimited   = int(C.RGTP_ERR_RATE_LIMITED)
+	ErrCodeNotSupported  = int(C.RGTP_ERR_NOT_SUPPORTED)
+	ErrCodeInternal      = int(C.RGTP_ERR_INTERNAL)
+	ErrCodeDropped       = int(C.RGTP_ERR_DROPPED)
+)
+
+// errCode returns the RGTP error code carried by err, or 0 if err is not
+// an *Error.
+func errCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
+
 func rgtpErr(code C.rgtp_error_t) error {
 	if code == C.RGTP_OK {
 		return nil
@@ -64,25 +120,74 @@ func Version() string {
 	return C.GoString(C.rgtp_version())
 }
 
+// ── Configuration ────────────────────────────────────────────────────────
+
+// Config selects socket and transfer parameters. Zero fields use the
+// library defaults, matching rgtp_config_t.
+type Config struct {
+	ChunkSize    uint32 // bytes per chunk (0 = auto: 1200 UDP)
+	WindowSize   uint32 // initial pull window in chunks (0 = 64)
+	FECEnabled   bool   // enable Reed-Solomon FEC
+	MerkleProofs bool   // include per-chunk Merkle proofs in responses
+	Port         uint16 // UDP port to bind (0 = auto-assign)
+	TimeoutMs    int    // operation timeout in ms (0 = 5000, -1 = infinite)
+}
+
+func (c *Config) toC() *C.rgtp_config_t {
+	if c == nil {
+		return nil
+	}
+	return &C.rgtp_config_t{
+		chunk_size:    C.uint32_t(c.ChunkSize),
+		window_size:   C.uint32_t(c.WindowSize),
+		fec_enabled:   C.bool(c.FECEnabled),
+		merkle_proofs: C.bool(c.MerkleProofs),
+		port:          C.uint16_t(c.Port),
+		timeout_ms:    C.int(c.TimeoutMs),
+	}
+}
+
 // ── Socket ───────────────────────────────────────────────────────────────
 
 // Socket wraps an rgtp_socket_t handle.
 type Socket struct {
 	ptr *C.rgtp_socket_t
+	cfg *Config // applied to every surface created on this socket
 }
 
-// NewSocket creates and binds an RGTP UDP socket.
+// NewSocket creates and binds an RGTP UDP socket with default settings.
 func NewSocket() (*Socket, error) {
+	return NewSocketWithConfig(nil)
+}
+
+// NewSocketWithConfig creates and binds an RGTP UDP socket. cfg (which may
+// be nil) also becomes the per-surface configuration for every Exposure
+// and pull started on the socket.
+func NewSocketWithConfig(cfg *Config) (*Socket, error) {
+	if cfg != nil {
+		c := *cfg
+		cfg = &c
+	}
 	var ptr *C.rgtp_socket_t
-	err := rgtpErr(C.rgtp_socket_create(nil, &ptr))
+	err := rgtpErr(C.rgtp_socket_create(cfg.toC(), &ptr))
 	if err != nil {
 		return nil, err
 	}
-	s := &Socket{ptr: ptr}
+	s := &Socket{ptr: ptr, cfg: cfg}
 	runtime.SetFinalizer(s, (*Socket).Close)
 	return s, nil
 }
 
+// Port returns the local UDP port the socket is bound to, including the
+// port the OS assigned when Config.Port was 0.
+func (s *Socket) Port() (uint16, error) {
+	var port C.uint16_t
+	if err := rgtpErr(C.rgtp_socket_get_port(s.ptr, &port)); err != nil {
+		return 0, err
+	}
+	return uint16(port), nil
+}
+
 // Close destroys the socket and releases all associated resources.
 func (s *Socket) Close() {
 	if s.ptr != nil {
@@ -95,7 +200,11 @@ func (s *Socket) Close() {
 
 // Surface wraps an rgtp_surface_t handle (exposer or puller).
 type Surface struct {
-	ptr *C.rgtp_surface_t
+	ptr     *C.rgtp_surface_t
+	start   time.Time // when the Exposure or pull began
+	exposer bool
+	layout  Layout // fixed once the surface exists; saves a cgo call per chunk
+	held    []bool // puller chunks kept from an earlier pull, by index
 }
 
 // Close destroys the surface and securely zeroizes all key material.
@@ -113,11 +222,64 @@ func (s *Surface) ExposureID() ([16]byte, error) {
 	return id, err
 }
 
+// Key returns the 32-byte AEAD key of an Exposure. The key never travels
+// on the wire: hand it to each puller out of band, for PullOptions.Key or
+// SetKey. Only exposer surfaces have a key to return.
+func (s *Surface) Key() ([32]byte, error) {
+	var key [32]byte
+	err := rgtpErr(C.rgtp_get_key(s.ptr, (*C.uint8_t)(unsafe.Pointer(&key[0]))))
+	return key, err
+}
+
+// SetKey installs the exposer's AEAD key on a puller surface. It must be
+// called before the first PullNext; without it every chunk fails
+// authentication.
+func (s *Surface) SetKey(key [32]byte) error {
+	return rgtpErr(C.rgtp_set_key(s.ptr, (*C.uint8_t)(unsafe.Pointer(&key[0]))))
+}
+
 // Progress returns the transfer completion fraction [0.0, 1.0].
 func (s *Surface) Progress() float32 {
 	return float32(C.rgtp_progress(s.ptr))
 }
 
+// Layout describes how an Exposure is split into chunks. Every chunk
+// except the last holds exactly ChunkSize plaintext bytes.
+type Layout struct {
+	TotalSize  uint64
+	ChunkCount uint32
+	ChunkSize  uint32
+}
+
+// Layout returns the chunk layout of this surface. For a puller it
+// reflects the Manifest received by PullStart.
+func (s *Surface) Layout() (Layout, error) {
+	var total C.uint64_t
+	var count, size C.uint32_t
+	err := rgtpErr(C.rgtp_get_layout(s.ptr, &total, &count, &size))
+	if err != nil {
+		return Layout{}, err
+	}
+	return Layout{
+		TotalSize:  uint64(total),
+		ChunkCount: uint32(count),
+		ChunkSize:  uint32(size),
+	}, nil
+}
+
+// skipChunk marks chunk idx of a puller surface as already held from an
+// earlier pull, so it is neither requested nor yielded by Chunks.
+func (s *Surface) skipChunk(idx uint32) error {
+	if err := rgtpErr(C.rgtp_pull_skip(s.ptr, C.uint32_t(idx))); err != nil {
+		return err
+	}
+	if s.held == nil {
+		s.held = make([]bool, s.layout.ChunkCount)
+	}
+	s.held[idx] = true
+	return nil
+}
+
 // Stats returns transfer statistics for this surface.
 func (s *Surface) Stats() (Stats, error) {
 	var cs C.rgtp_stats_t
@@ -125,6 +287,16 @@ func (s *Surface) Stats() (Stats, error) {
 	if err != nil {
 		return Stats{}, err
 	}
+	var merkleFailures C.uint32_t
+	if err := rgtpErr(C.rgtp_get_merkle_failures(s.ptr, &merkleFailures)); err != nil {
+		return Stats{}, err
+	}
+	var window, peak C.uint32_t
+	if err := rgtpErr(C.rgtp_get_window(s.ptr, &window, &peak, nil)); err != nil {
+		return Stats{}, err
+	}
+	bytes := int64(cs.bytes_sent) + int64(cs.bytes_received)
+	mbps := safeThroughput(bytes, time.Since(s.start)) * 8 / 1e6
 	return Stats{
 		BytesSent:        uint64(cs.bytes_sent),
 		BytesReceived:    uint64(cs.bytes_received),
@@ -132,12 +304,59 @@ func (s *Surface) Stats() (Stats, error) {
 		ChunksReceived:   uint32(cs.chunks_received),
 		AuthFailures:     uint32(cs.auth_failures),
 		MalformedPackets: uint32(cs.malformed_packets),
+		CorruptChunks:    uint32(cs.auth_failures) + uint32(merkleFailures),
+		FECRecoveries:    uint32(cs.fec_recoveries),
+		NAKSent:          uint32(cs.nak_sent),
 		PacketLossRate:   float32(cs.packet_loss_rate),
 		RTTUs:            uint32(cs.rtt_us),
+		PullPressure:     uint32(cs.pull_pressure),
+		ThroughputMbps:   mbps,
+		Window:           uint32(window),
+		PeakWindow:       uint32(peak),
+		Encrypted:        s.exposer || cs.chunks_received > 0,
 	}, nil
 }
 
+// Window returns a puller's current pull window in chunks: how many
+// missing chunks one NAK asks for. Congestion control adapts it between
+// its floor and 65536.
+func (s *Surface) Window() (uint32, error) {
+	var window C.uint32_t
+	err := rgtpErr(C.rgtp_get_window(s.ptr, &window, nil, nil))
+	return uint32(window), err
+}
+
+// SetWindow overrides a puller's pull window with window chunks and pins
+// floor as the smallest window congestion control may shrink it to. It
+// may still grow above window. Use it on links known to sustain more
+// than the adaptive window allows; 1 <= floor <= window <= 65536.
+func (s *Surface) SetWindow(window, floor uint32) error {
+	return rgtpErr(C.rgtp_set_window(s.ptr, C.uint32_t(window), C.uint32_t(floor)))
+}
+
+// safeThroughput returns bytes per second over d. Durations below one
+// nanosecond, which a fast transfer can measure as zero, are floored so
+// the result is never +Inf or NaN; zero bytes always yields zero.
+func safeThroughput(bytes int64, d time.Duration) float64 {
+	if bytes <= 0 {
+		return 0
+	}
+	if d < time.Nanosecond {
+		d = time.Nanosecond
+	}
+	return float64(bytes) / d.Seconds()
+}
+
 // Stats holds per-surface transfer statistics.
+//
+// BytesSent and BytesReceived count whole packets, headers, AEAD tags and
+// Merkle proofs included, not just payload. ThroughputMbps averages them
+// over the surface's lifetime, so time spent idle before or between pulls
+// lowers it.
+//
+// PacketLossRate, RTTUs and PullPressure are the inputs the C layer uses
+// for congestion control; sampling them over time shows how the pull
+// window is being driven. Window and PeakWindow show where it stands.
 type Stats struct {
 	BytesSent        uint64
 	BytesReceived    uint64
@@ -145,17 +364,74 @@ type Stats struct {
 	ChunksReceived   uint32
 	AuthFailures     uint32
 	MalformedPackets uint32
-	PacketLossRate   float32
-	RTTUs            uint32
+	CorruptChunks    uint32  // chunks rejected by AEAD or Merkle checks (puller)
+	FECRecoveries    uint32  // chunks recovered via FEC
+	NAKSent          uint32  // NAK packets sent (puller)
+	PacketLossRate   float32 // EWMA packet loss rate [0.0, 1.0]
+	RTTUs            uint32  // EWMA RTT estimate in microseconds
+	PullPressure     uint32  // pull requests received in the last 100ms (exposer)
+	ThroughputMbps   float64 // BytesSent+BytesReceived per second since the surface was created
+
+	Window     uint32 // current pull window in chunks (puller)
+	PeakWindow uint32 // largest pull window reached (puller)
+
+	// Encrypted reports that the transfer's chunks travelled AEAD
+	// encrypted and were checked against the Exposure's key: always for
+	// an exposer, and for a puller once a chunk has authenticated.
+	Encrypted bool
+}
+
+// LatencyStats summarises the puller's recent one-way delay samples
+// (the last 256 chunks). All durations are in microseconds.
+type LatencyStats struct {
+	MeanUs      uint32
+	JitterUs    uint32 // standard deviation of the samples
+	P50Us       uint32
+	P90Us       uint32
+	P99Us       uint32
+	MinUs       uint32
+	MaxUs       uint32
+	SampleCount uint32
+}
+
+// LatencyStats returns delay percentiles for this surface. Before any
+// chunk has been received every field is zero.
+func (s *Surface) LatencyStats() (LatencyStats, error) {
+	var cl C.rgtp_latency_stats_t
+	if err := rgtpErr(C.rgtp_get_latency_stats(s.ptr, &cl)); err != nil {
+		return LatencyStats{}, err
+	}
+	var p50, p90 C.uint32_t
+	if err := rgtpErr(C.rgtp_get_latency_percentile(s.ptr, 50, &p50)); err != nil {
+		return LatencyStats{}, err
+	}
+	if err := rgtpErr(C.rgtp_get_latency_percentile(s.ptr, 90, &p90)); err != nil {
+		return LatencyStats{}, err
+	}
+	return LatencyStats{
+		MeanUs:      uint32(cl.mean_us),
+		JitterUs:    uint32(cl.jitter_us),
+		P50Us:       uint32(p50),
+		P90Us:       uint32(p90),
+		P99Us:       uint32(cl.p99_us),
+		MinUs:       uint32(cl.min_us),
+		MaxUs:       uint32(cl.max_us),
+		SampleCount: uint32(cl.sample_count),
+	}, nil
 }
 
 // ── Exposer API ──────────────────────────────────────────────────────────
 
+// ErrEmptyData is returned by Expose for a zero-length payload. An
+// Exposure always has at least one chunk; callers that may produce empty
+// payloads should check for this error rather than special-casing length.
+var ErrEmptyData = errors.New("data must not be empty")
+
 // Expose pre-encrypts data and creates an immutable Exposure.
 // The returned Surface must be polled to serve pull requests.
 func Expose(ctx context.Context, sock *Socket, data []byte) (*Surface, error) {
 	if len(data) == 0 {
-		return nil, errors.New("data must not be empty")
+		return nil, ErrEmptyData
 	}
 
 	var pinner runtime.Pinner
@@ -167,14 +443,18 @@ func Expose(ctx context.Context, sock *Socket, data []byte) (*Surface, error) {
 		sock.ptr,
 		unsafe.Pointer(&data[0]),
 		C.size_t(len(data)),
-		nil,
+		sock.cfg.toC(),
 		&ptr,
 	))
 	if err != nil {
 		return nil, err
 	}
 
-	s := &Surface{ptr: ptr}
+	s := &Surface{ptr: ptr, start: time.Now(), exposer: true}
+	if s.layout, err = s.Layout(); err != nil {
+		s.Close()
+		return nil, err
+	}
 	runtime.SetFinalizer(s, (*Surface).Close)
 	return s, nil
 }
@@ -192,7 +472,8 @@ func Poll(ctx context.Context, surface *Surface, timeoutMs int) error {
 
 // ── Puller API ───────────────────────────────────────────────────────────
 
-// PullStart begins pulling an Exposure from a remote Exposer.
+// PullStart begins pulling an Exposure from a remote Exposer. Install the
+// Exposure's key with SetKey before receiving chunks.
 func PullStart(ctx context.Context, sock *Socket, server net.Addr,
 	exposureID [16]byte) (*Surface, error) {
 
@@ -223,14 +504,18 @@ func PullStart(ctx context.Context, sock *Socket, server net.Addr,
 		sock.ptr,
 		&ss,
 		(*C.uint8_t)(unsafe.Pointer(&exposureID[0])),
-		nil,
+		sock.cfg.toC(),
 		&ptr,
 	))
 	if err != nil {
 		return nil, err
 	}
 
-	s := &Surface{ptr: ptr}
+	s := &Surface{ptr: ptr, start: time.Now()}
+	if s.layout, err = s.Layout(); err != nil {
+		s.Close()
+		return nil, err
+	}
 	runtime.SetFinalizer(s, (*Surface).Close)
 	return s, nil
 }
@@ -241,40 +526,70 @@ type ChunkResult struct {
 	ChunkIndex uint32
 }
 
-// PullNext receives the next available chunk.
+// PullNext receives the next available chunk into a freshly allocated
+// buffer of bufSize bytes (0 = 65536).
 // Returns context.Canceled if ctx is cancelled.
 func PullNext(ctx context.Context, surface *Surface, bufSize int) (ChunkResult, error) {
-	select {
-	case <-ctx.Done():
-		return ChunkResult{}, ctx.Err()
-	default:
-	}
-
 	if bufSize <= 0 {
 		bufSize = 65536
 	}
 
 	buf := make([]byte, bufSize)
-	var received C.size_t
-	var chunkIndex C.uint32_t
-
-	var pinner runtime.Pinner
-	pinner.Pin(&buf[0])
-	defer pinner.Unpin()
-
-	err := rgtpErr(C.rgtp_pull_next(
-		surface.ptr,
-		unsafe.Pointer(&buf[0]),
-		C.size_t(bufSize),
-		&received,
-		&chunkIndex,
-	))
+	n, chunkIndex, err := PullNextInto(ctx, surface, buf)
 	if err != nil {
 		return ChunkResult{}, err
 	}
 
 	return ChunkResult{
-		Data:       buf[:int(received)],
-		ChunkIndex: uint32(chunkIndex),
+		Data:       buf[:n],
+		ChunkIndex: chunkIndex,
 	}, nil
 }
+
+// ErrBufferTooSmall is matched by errors.Is for any *BufferTooSmallError.
+var ErrBufferTooSmall = errors.New("rgtp: buffer too small")
+
+// BufferTooSmallError is returned by PullNextInto when the caller's buffer
+// cannot hold a full chunk. Needed is the minimum buffer size.
+type BufferTooSmallError struct {
+	Needed int
+}
+
+func (e *BufferTooSmallError) Error() string {
+	return fmt.Sprintf("rgtp: buffer too small: need %d bytes", e.Needed)
+}
+
+func (e *BufferTooSmallError) Is(target error) bool {
+	return target == ErrBufferTooSmall
+}
+
+// PullNextInto receives the next available chunk into buf and returns the
+// number of bytes written and the chunk index. Unlike PullNext it does not
+// allocate when a chunk is received, so one buffer can be reused across
+// calls.
+//
+// buf must hold at least Layout().ChunkSize bytes; otherwise a
+// *BufferTooSmallError carrying the required size is returned and nothing
+// is received. Returns context.Canceled if ctx is cancelled.
+func PullNextInto(ctx context.Context, surface *Surface, buf []byte) (int, uint32, error) {
+	select {
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	default:
+	}
+
+	if surface == nil || surface.ptr == nil {
+		return 0, 0, errors.New("surface is closed")
+	}
+	chunkSize := int(surface.layout.ChunkSize)
+	if len(buf) == 0 || len(buf) < chunkSize {
+		return 0, 0, &BufferTooSmallError{Needed: chunkSize}
+	}
+
+	// cgo keeps buf pinned for the duration of the call
+	r := C.rgtp_go_pull_next(surface.ptr, unsafe.Pointer(&buf[0]), C.size_t(len(buf)))
+	if err := rgtpErr(r.err); err != nil {
+		return 0, 0, err
+	}
+	return int(r.received), uint32(r.chunk_index), nil
+}
@@ -7,9 +7,18 @@
 package rgtp
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math"
 	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -78,6 +87,15 @@ func TestRgtpErrorIsError(t *testing.T) {
 	}
 }
 
+func TestErrCode(t *testing.T) {
+	if got := errCode(&Error{Code: ErrCodeTimeout}); got != ErrCodeTimeout {
+		t.Errorf("Expected code %d, got %d", ErrCodeTimeout, got)
+	}
+	if got := errCode(errors.New("plain")); got != 0 {
+		t.Errorf("Non-RGTP error must map to code 0, got %d", got)
+	}
+}
+
 func TestRgtpErrOK(t *testing.T) {
 	// rgtpErr(RGTP_OK) must return nil
 	// We test this indirectly via Init()
@@ -102,6 +120,56 @@ func TestNewSocket(t *testing.T) {
 	}
 }
 
+func TestNewSocketWithConfig(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	cfg := &Config{ChunkSize: 1000, TimeoutMs: 100}
+	sock, err := NewSocketWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewSocketWithConfig() failed: %v", err)
+	}
+	defer sock.Close()
+
+	// The socket keeps its own copy of the configuration
+	cfg.ChunkSize = 1
+	if sock.cfg.ChunkSize != 1000 {
+		t.Errorf("Socket config must not alias the caller's, got ChunkSize %d", sock.cfg.ChunkSize)
+	}
+
+	surface, err := Expose(context.Background(), sock, make([]byte, 2500))
+	if err != nil {
+		t.Skip("Expose failed:", err)
+	}
+	defer surface.Close()
+	layout, err := surface.Layout()
+	if err != nil {
+		t.Fatalf("Layout() failed: %v", err)
+	}
+	if layout.ChunkSize != 1000 || layout.ChunkCount != 3 {
+		t.Errorf("Expected 3 chunks of 1000 bytes, got %+v", layout)
+	}
+}
+
+func TestSocketPortAutoAssigned(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocket()
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	port, err := sock.Port()
+	if err != nil {
+		t.Fatalf("Port() failed: %v", err)
+	}
+	if port == 0 {
+		t.Error("Port() must report the OS-assigned port, got 0")
+	}
+}
+
 func TestSocketCloseIdempotent(t *testing.T) {
 	if err := Init(); err != nil {
 		t.Skip("Init failed:", err)
@@ -143,6 +211,13 @@ func TestExposeEmptyDataReturnsError(t *testing.T) {
 	if err == nil {
 		t.Error("Expose with empty data must return an error")
 	}
+	if !errors.Is(err, ErrEmptyData) {
+		t.Errorf("Expected ErrEmptyData, got %v", err)
+	}
+	_, err = Expose(ctx, sock, nil)
+	if !errors.Is(err, ErrEmptyData) {
+		t.Errorf("Expose(nil) must return ErrEmptyData, got %v", err)
+	}
 }
 
 func TestExposeValidData(t *testing.T) {
@@ -279,6 +354,60 @@ func TestSurfaceStats(t *testing.T) {
 	}
 	// Freshly created exposer: bytes_sent should be 0
 	_ = stats
+	// NAKs are only ever sent by pullers
+	if stats.NAKSent != 0 {
+		t.Errorf("Exposer NAKSent must be 0, got %d", stats.NAKSent)
+	}
+	if !stats.Encrypted {
+		t.Error("An Exposure is always encrypted")
+	}
+}
+
+func TestLatencyStatsEmpty(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocket()
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	surface, err := Expose(context.Background(), sock, []byte("latency"))
+	if err != nil {
+		t.Skip("Expose failed:", err)
+	}
+	defer surface.Close()
+
+	// An exposer never records delay samples
+	ls, err := surface.LatencyStats()
+	if err != nil {
+		t.Fatalf("LatencyStats failed: %v", err)
+	}
+	if ls != (LatencyStats{}) {
+		t.Errorf("LatencyStats with no samples = %+v, want zero", ls)
+	}
+}
+
+func TestSafeThroughput(t *testing.T) {
+	cases := []struct {
+		bytes int64
+		d     time.Duration
+		want  float64
+	}{
+		{1000, time.Second, 1000},
+		{1000, 0, 1000 / time.Nanosecond.Seconds()},
+		{1000, -time.Second, 1000 / time.Nanosecond.Seconds()},
+		{0, 0, 0},
+	}
+	for _, c := range cases {
+		got := safeThroughput(c.bytes, c.d)
+		if math.IsInf(got, 0) || math.IsNaN(got) {
+			t.Errorf("safeThroughput(%d, %v) = %v, must be finite", c.bytes, c.d, got)
+		} else if got != c.want {
+			t.Errorf("safeThroughput(%d, %v) = %v, want %v", c.bytes, c.d, got, c.want)
+		}
+	}
 }
 
 func TestSurfaceCloseIdempotent(t *testing.T) {
@@ -357,6 +486,60 @@ func TestPollTimeout(t *testing.T) {
 	}
 }
 
+func TestServeContextCancelled(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocket()
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	surface, err := Expose(context.Background(), sock, make([]byte, 256))
+	if err != nil {
+		t.Skip("Expose failed:", err)
+	}
+	defer surface.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = Serve(ctx, surface, ServeOptions{PollTimeoutMs: 10, MaxBytesPerSec: 1 << 20})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Serve must return the context error, got %v", err)
+	}
+}
+
+func TestTokenBucketHonoursCap(t *testing.T) {
+	const rate = 1 << 20     // 1 MiB/s
+	const perPoll = 64 << 10 // bytes sent by each simulated poll
+	const total = 20 << 20
+
+	now := time.Unix(0, 0)
+	start := now
+	b := newTokenBucket(rate, now)
+	for sent := 0; sent < total; sent += perPoll {
+		now = now.Add(b.take(perPoll, now))
+	}
+
+	got := float64(total) / now.Sub(start).Seconds()
+	// One second of burst is allowed up front, so allow a little headroom
+	if got > rate*1.1 || got < rate*0.9 {
+		t.Errorf("Effective rate %.0f B/s outside 10%% of cap %d B/s", got, rate)
+	}
+}
+
+func TestTokenBucketNoWaitUnderCap(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(1000, now)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		if wait := b.take(500, now); wait != 0 {
+			t.Fatalf("Sending under the cap must not wait, got %v", wait)
+		}
+	}
+}
+
 // ── PullStart ────────────────────────────────────────────────────────────
 
 func TestPullStartContextCancelled(t *testing.T) {
@@ -459,6 +642,860 @@ func TestPullNextDefaultBufSize(t *testing.T) {
 	_ = err
 }
 
+// ── PullToFile ───────────────────────────────────────────────────────────
+
+func TestPullToFileCancelledLeavesNoFile(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocket()
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	addr, _ := net.ResolveUDPAddr("udp", "127.0.0.1:19999")
+	var id [16]byte
+	if err := PullToFile(ctx, sock, addr, id, path, &PullOptions{Key: [32]byte{1}}); err == nil {
+		t.Fatal("PullToFile with cancelled context must return an error")
+	}
+	for _, p := range []string{path, path + PartialSuffix} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s must not exist after a cancelled pull", p)
+		}
+	}
+}
+
+func TestPullToFileResume(t *testing.T) {
+	data := loopbackData(8*1200 + 100)
+	exposer, addr := exposeLoopback(t, nil, data)
+	id, _ := exposer.ExposureID()
+	key, _ := exposer.Key()
+	// Chunk 3 never gets through, so the first pull stalls
+	lossy := relay(t, addr, func(idx uint32, pkt []byte) bool { return idx != 3 })
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 100})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "out.bin")
+	partial, state := path+PartialSuffix, path+PartialSuffix+StateSuffix
+	opts := &PullOptions{Key: key, AllowPartial: true}
+
+	err = PullToFile(ctx, sock, lossy, id, path, opts)
+	var missing *MissingChunksError
+	if !errors.As(err, &missing) || len(missing.Missing) != 1 || missing.Missing[0] != 3 {
+		t.Fatalf("Lossy PullToFile must report chunk 3 missing, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s must not exist after an incomplete pull", path)
+	}
+	for _, p := range []string{partial, state} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("%s must be kept with AllowPartial: %v", p, err)
+		}
+	}
+
+	// Mark chunk 0 on disk: a held chunk must not be pulled again
+	f, err := os.OpenFile(partial, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte{^data[0]}, 0)
+	f.Close()
+
+	if err := ResumePull(ctx, sock, addr, id, path, opts); err != nil {
+		t.Fatalf("ResumePull() failed: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{^data[0]}, data[1:]...)
+	if !bytes.Equal(got, want) {
+		t.Error("Resumed file must keep held chunks and fill in the rest")
+	}
+	for _, p := range []string{partial, state} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s must be removed once the pull completes", p)
+		}
+	}
+}
+
+func TestResumePullRejectsOtherExposure(t *testing.T) {
+	exposer, addr := exposeLoopback(t, nil, loopbackData(4*1200))
+	id, _ := exposer.ExposureID()
+	key, _ := exposer.Key()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	p, err := createPartial(path, [16]byte{1}, exposer.layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.keep(); err != nil {
+		t.Fatal(err)
+	}
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 500})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = ResumePull(ctx, sock, addr, id, path, &PullOptions{Key: key})
+	if !errors.Is(err, ErrPartialMismatch) {
+		t.Fatalf("ResumePull over another Exposure's partial file: got %v, want ErrPartialMismatch", err)
+	}
+	if _, err := os.Stat(path + PartialSuffix); err != nil {
+		t.Errorf("A mismatched partial file must be left alone: %v", err)
+	}
+}
+
+func TestCorruptChunkIsRetryable(t *testing.T) {
+	// A bit-flipped chunk surfaces as an AEAD or Merkle failure; the pull
+	// loop must discard it and keep going rather than abort.
+	for _, code := range []int{ErrCodeAuthFail, ErrCodeMerkleFail, ErrCodeDropped} {
+		if !isRetryable(&Error{Code: code}) {
+			t.Errorf("Error code %d must be retryable", code)
+		}
+	}
+	// A caller mistake, such as a buffer too small for the chunk, must
+	// not be retried forever
+	for _, code := range []int{ErrCodeNoMem, ErrCodeSocket, ErrCodeChunkIndexOOB, ErrCodeInvalidArg} {
+		if isRetryable(&Error{Code: code}) {
+			t.Errorf("Error code %d must be fatal", code)
+		}
+	}
+}
+
+func TestPullContextCancelled(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocket()
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	addr, _ := net.ResolveUDPAddr("udp", "127.0.0.1:19999")
+	var id [16]byte
+	data, err := Pull(ctx, sock, addr, id, &PullOptions{MaxBytes: 1 << 20, Key: [32]byte{1}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Pull with cancelled context: got %v, want context.Canceled", err)
+	}
+	if data != nil {
+		t.Error("A failed Pull must not return data")
+	}
+}
+
+func TestChunksClosedSurfaceYieldsOneError(t *testing.T) {
+	var errs int
+	for c, err := range Chunks(context.Background(), &Surface{}) {
+		if err == nil {
+			t.Fatalf("closed surface yielded chunk %d", c.ChunkIndex)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("Chunks on a closed surface yielded %d errors, want 1", errs)
+	}
+}
+
+func TestCheckChunksComplete(t *testing.T) {
+	layout := Layout{TotalSize: 10, ChunkCount: 3, ChunkSize: 4}
+	chunks := map[uint32][]byte{
+		2: {8, 9},
+		0: {0, 1, 2, 3},
+		1: {4, 5, 6, 7},
+	}
+	if err := checkChunks(layout, chunks); err != nil {
+		t.Fatalf("checkChunks() on a complete set failed: %v", err)
+	}
+}
+
+func TestCheckChunksReportsGaps(t *testing.T) {
+	layout := Layout{TotalSize: 16, ChunkCount: 4, ChunkSize: 4}
+	chunks := map[uint32][]byte{2: {8, 9, 10, 11}}
+
+	err := checkChunks(layout, chunks)
+	var missing *MissingChunksError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected *MissingChunksError, got %v", err)
+	}
+	want := []uint32{0, 1, 3}
+	if len(missing.Missing) != len(want) {
+		t.Fatalf("Expected missing %v, got %v", want, missing.Missing)
+	}
+	for i := range want {
+		if missing.Missing[i] != want[i] {
+			t.Errorf("Expected missing %v, got %v", want, missing.Missing)
+			break
+		}
+	}
+}
+
+func TestCheckChunksRejectsOverrun(t *testing.T) {
+	layout := Layout{TotalSize: 6, ChunkCount: 2, ChunkSize: 4}
+	chunks := map[uint32][]byte{0: {0, 1, 2, 3}, 1: {4, 5, 6, 7}}
+	err := checkChunks(layout, chunks)
+	if err == nil {
+		t.Fatal("A final chunk past TotalSize must be rejected")
+	}
+	var missing *MissingChunksError
+	if errors.As(err, &missing) {
+		t.Errorf("Overrun must not be reported as missing chunks: %v", err)
+	}
+}
+
+func TestMissingChunksErrorUnwrap(t *testing.T) {
+	err := &MissingChunksError{Missing: []uint32{1}, ChunkCount: 2, Err: context.Canceled}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("MissingChunksError must unwrap to the cause that stopped the pull")
+	}
+}
+
+// ── Loopback transfer ────────────────────────────────────────────────────
+
+// exposeLoopback exposes data on a socket configured by cfg (which may
+// be nil) and serves it until the test ends. The socket binds an
+// OS-assigned port unless cfg sets Port. It returns the exposer surface
+// and its address.
+func exposeLoopback(t testing.TB, cfg *Config, data []byte) (*Surface, *net.UDPAddr) {
+	t.Helper()
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocketWithConfig(cfg)
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	port, err := sock.Port()
+	if err != nil {
+		sock.Close()
+		t.Fatalf("Port() failed: %v", err)
+	}
+	surface, err := Expose(context.Background(), sock, data)
+	if err != nil {
+		sock.Close()
+		t.Fatalf("Expose() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Serve(ctx, surface, ServeOptions{PollTimeoutMs: 10})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		surface.Close()
+		sock.Close()
+	})
+	return surface, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)}
+}
+
+// loopbackData returns n pseudo-random bytes.
+func loopbackData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i*7 + i>>8)
+	}
+	return data
+}
+
+func TestPullLoopback(t *testing.T) {
+	// More chunks than one pull window, and than the 256-entry replay
+	// window, so later chunks are fetched by NAK
+	data := loopbackData(300*1200 + 100)
+	exposer, addr := exposeLoopback(t, nil, data)
+	id, _ := exposer.ExposureID()
+	key, err := exposer.Key()
+	if err != nil {
+		t.Fatalf("Key() failed: %v", err)
+	}
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 500})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got, err := Pull(ctx, sock, addr, id, &PullOptions{Key: key})
+	if err != nil {
+		t.Fatalf("Pull() failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Pulled data does not match the Exposure")
+	}
+}
+
+func TestPullWithoutKeyFailsAtStart(t *testing.T) {
+	exposer, addr := exposeLoopback(t, nil, loopbackData(4*1200))
+	id, _ := exposer.ExposureID()
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 2000})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, opts := range []*PullOptions{nil, {MaxBytes: 1 << 20}} {
+		data, err := Pull(ctx, sock, addr, id, opts)
+		if !errors.Is(err, ErrNoKey) {
+			t.Errorf("Pull with opts %+v: got %v, want ErrNoKey", opts, err)
+		}
+		if data != nil {
+			t.Error("A failed Pull must not return data")
+		}
+	}
+}
+
+func TestPullWithWrongKeyFailsFast(t *testing.T) {
+	// Every chunk fails authentication. The pull must say so after the
+	// first chunks instead of stalling through its receive timeouts.
+	exposer, addr := exposeLoopback(t, nil, loopbackData(8*1200))
+	id, _ := exposer.ExposureID()
+	key, _ := exposer.Key()
+	key[0] ^= 0xff
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 2000})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	path := filepath.Join(t.TempDir(), "out.bin")
+	err = PullToFile(ctx, sock, addr, id, path, &PullOptions{Key: key})
+	if !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("Pull with the wrong key: got %v, want ErrKeyMismatch", err)
+	}
+	if errCode(err) != ErrCodeAuthFail {
+		t.Errorf("ErrKeyMismatch must wrap the AEAD failure, got %v", err)
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Errorf("Wrong key took %v to detect; it must not wait for a timeout", d)
+	}
+	var missing *MissingChunksError
+	if errors.As(err, &missing) {
+		t.Error("A wrong key is not a transfer gap")
+	}
+}
+
+// relay forwards datagrams between a puller and upstream. Each chunk
+// packet from upstream is first passed to tamper with its chunk index;
+// it is dropped if tamper returns false.
+func relay(t testing.TB, upstream *net.UDPAddr, tamper func(idx uint32, pkt []byte) bool) *net.UDPAddr {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip("ListenUDP failed:", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var puller *net.UDPAddr
+		buf := make([]byte, 65536)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			pkt := buf[:n]
+			if !from.IP.Equal(upstream.IP) || from.Port != upstream.Port {
+				puller = from
+				conn.WriteToUDP(pkt, upstream)
+				continue
+			}
+			// pkt[1] is the packet type; bytes 20-23 hold the chunk index
+			isChunk := n > 24 && (pkt[1] == 0x03 || pkt[1] == 0x04)
+			if isChunk && !tamper(binary.BigEndian.Uint32(pkt[20:24]), pkt) {
+				continue
+			}
+			if puller != nil {
+				conn.WriteToUDP(pkt, puller)
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		conn.Close()
+		<-done
+	})
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+// flipRelay is a relay that flips one byte of the first chunk packet for
+// chunk index 3. flipAt picks the byte's offset within that packet.
+func flipRelay(t testing.TB, upstream *net.UDPAddr, flipAt func(pkt []byte) int) *net.UDPAddr {
+	t.Helper()
+	flipped := false
+	return relay(t, upstream, func(idx uint32, pkt []byte) bool {
+		if !flipped && idx == 3 {
+			pkt[flipAt(pkt)] ^= 0x01
+			flipped = true
+		}
+		return true
+	})
+}
+
+func TestPullRecoversBitFlippedChunk(t *testing.T) {
+	// A chunk corrupted in flight must be counted, discarded and
+	// re-requested, and the pull must still deliver the exact data.
+	tests := []struct {
+		name     string
+		flipAt   func(pkt []byte) int
+		authFail uint32
+	}{
+		// The last byte is part of the AEAD tag
+		{"payload", func(pkt []byte) int { return len(pkt) - 1 }, 1},
+		// Byte 25 is the first proof byte; the ciphertext stays intact
+		{"merkle proof", func(pkt []byte) int { return 25 }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := loopbackData(8 * 1200)
+			exposer, addr := exposeLoopback(t, &Config{MerkleProofs: true}, data)
+			id, _ := exposer.ExposureID()
+			key, _ := exposer.Key()
+			relay := flipRelay(t, addr, tt.flipAt)
+
+			sock, err := NewSocketWithConfig(&Config{TimeoutMs: 500})
+			if err != nil {
+				t.Skip("NewSocket failed:", err)
+			}
+			defer sock.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			got, stats, err := pullAll(ctx, sock, relay, id, &PullOptions{Key: key})
+			if err != nil {
+				t.Fatalf("Pull through a corrupting link failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("Pulled data does not match the Exposure")
+			}
+			if stats.CorruptChunks != 1 || stats.AuthFailures != tt.authFail {
+				t.Errorf("Expected 1 corrupt chunk (%d AEAD), got %d (%d AEAD)",
+					tt.authFail, stats.CorruptChunks, stats.AuthFailures)
+			}
+			if stats.NAKSent == 0 {
+				t.Error("The corrupt chunk must be re-requested with a NAK")
+			}
+			if !stats.Encrypted {
+				t.Error("Stats must report the pull as encrypted")
+			}
+		})
+	}
+}
+
+func TestChunksExposerSurfaceYieldsOneError(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Skip("Init failed:", err)
+	}
+	sock, err := NewSocket()
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+	surface, err := Expose(context.Background(), sock, make([]byte, 256))
+	if err != nil {
+		t.Skip("Expose failed:", err)
+	}
+	defer surface.Close()
+
+	var errs int
+	for _, err := range Chunks(context.Background(), surface) {
+		if err == nil {
+			t.Fatal("exposer surface yielded a chunk")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("Chunks on an exposer surface yielded %d errors, want 1", errs)
+	}
+}
+
+// ── Directory transfer ───────────────────────────────────────────────────
+
+func TestPackUnpackDirectoryRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub", "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(src, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"top.txt":           "top",
+		"sub/deep/leaf.bin": "leaf",
+		"sub/zero":          "",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("deep/leaf.bin", filepath.Join(src, "sub", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := packDirectory(src)
+	if err != nil {
+		t.Fatalf("packDirectory() failed: %v", err)
+	}
+	dst := t.TempDir()
+	if err := unpackDirectory(bytes.NewReader(data), dst); err != nil {
+		t.Fatalf("unpackDirectory() failed: %v", err)
+	}
+
+	for name, body := range files {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("%s not restored: %v", name, err)
+			continue
+		}
+		if string(got) != body {
+			t.Errorf("%s: expected %q, got %q", name, body, got)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dst, "empty")); err != nil || !info.IsDir() {
+		t.Errorf("Empty directory not restored: %v", err)
+	}
+	link, err := os.Readlink(filepath.Join(dst, "sub", "link"))
+	if err != nil || link != "deep/leaf.bin" {
+		t.Errorf("Symlink not restored as a link: %q, %v", link, err)
+	}
+}
+
+func TestPackDirectoryRejectsEscapingSymlink(t *testing.T) {
+	src := t.TempDir()
+	if err := os.Symlink("../outside", filepath.Join(src, "bad")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := packDirectory(src); err == nil {
+		t.Error("A symlink pointing outside the tree must be rejected")
+	}
+}
+
+func TestUnpackDirectoryRejectsTraversal(t *testing.T) {
+	dir := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Mode: 0o755, Typeflag: tar.TypeDir}
+	}
+	link := func(name, target string) *tar.Header {
+		return &tar.Header{Name: name, Linkname: target, Typeflag: tar.TypeSymlink}
+	}
+	file := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Mode: 0o644, Size: 1, Typeflag: tar.TypeReg}
+	}
+
+	cases := []struct {
+		name    string
+		entries []*tar.Header
+	}{
+		{"dotdot entry", []*tar.Header{file("../evil")}},
+		// Each link target is inside the tree as text, but x/y resolves
+		// to the root, so x/y/z would be the root's parent.
+		{"symlink chain", []*tar.Header{
+			dir("x/"), link("x/y", ".."), link("x/y/z", ".."), file("x/y/z/evil"),
+		}},
+		// s resolves to the root, so s/.. is its parent
+		{"dotdot after symlink", []*tar.Header{
+			link("s", "."), link("t", "s/../evil"), file("t"),
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, h := range c.entries {
+				tw.WriteHeader(h)
+				if h.Typeflag == tar.TypeReg {
+					tw.Write([]byte("x"))
+				}
+			}
+			tw.Close()
+
+			// Unpack one level down so an escape lands in a fresh directory
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "dst")
+			if err := unpackDirectory(&buf, dst); err == nil {
+				t.Error("An entry outside the destination must be rejected")
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "evil")); !os.IsNotExist(err) {
+				t.Error("Traversal entry must not be written")
+			}
+		})
+	}
+}
+
+func TestReceiveDirectoryLoopback(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	big := loopbackData(5*1200 + 17)
+	if err := os.WriteFile(filepath.Join(src, "sub", "big.bin"), big, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := packDirectory(src)
+	if err != nil {
+		t.Fatalf("packDirectory() failed: %v", err)
+	}
+	exposer, addr := exposeLoopback(t, nil, data)
+	id, _ := exposer.ExposureID()
+	key, _ := exposer.Key()
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 100})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The tar stream is staged on disk under TMPDIR and removed afterwards
+	staging := t.TempDir()
+	t.Setenv("TMPDIR", staging)
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	stats, err := ReceiveDirectory(ctx, sock, addr, id, dst, &PullOptions{Key: key})
+	if err != nil {
+		t.Fatalf("ReceiveDirectory() failed: %v", err)
+	}
+	if stats.ChunksReceived == 0 || !stats.Encrypted {
+		t.Errorf("Stats must cover the pull: %+v", stats)
+	}
+	got, err := os.ReadFile(filepath.Join(dst, "sub", "big.bin"))
+	if err != nil || !bytes.Equal(got, big) {
+		t.Errorf("sub/big.bin not restored intact: %v", err)
+	}
+	if left, _ := os.ReadDir(staging); len(left) != 0 {
+		t.Errorf("Staging files left behind: %v", left)
+	}
+
+	_, err = ReceiveDirectory(ctx, sock, addr, id, t.TempDir(), &PullOptions{Key: key, MaxBytes: 1024})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ReceiveDirectory over MaxBytes: got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestSurfaceSetWindow(t *testing.T) {
+	exposer, addr := exposeLoopback(t, nil, loopbackData(4*1200))
+	id, _ := exposer.ExposureID()
+	key, _ := exposer.Key()
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 500})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	surface, err := startPull(ctx, sock, addr, id, &PullOptions{Key: key})
+	if err != nil {
+		t.Fatalf("PullStart() failed: %v", err)
+	}
+	defer surface.Close()
+
+	if w, err := surface.Window(); err != nil || w != 64 {
+		t.Errorf("Default Window() = %d, %v; want 64", w, err)
+	}
+	if err := surface.SetWindow(256, 128); err != nil {
+		t.Fatalf("SetWindow() failed: %v", err)
+	}
+	stats, err := surface.Stats()
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if stats.Window != 256 || stats.PeakWindow != 256 {
+		t.Errorf("Stats window %d, peak %d; want 256, 256", stats.Window, stats.PeakWindow)
+	}
+
+	if err := surface.SetWindow(64, 128); errCode(err) != ErrCodeInvalidArg {
+		t.Errorf("A floor above the window must be rejected, got %v", err)
+	}
+	if err := exposer.SetWindow(256, 128); errCode(err) != ErrCodeInvalidArg {
+		t.Errorf("An exposer has no pull window to set, got %v", err)
+	}
+}
+
+func TestPullNextIntoBufferTooSmall(t *testing.T) {
+	exposer, addr := exposeLoopback(t, nil, loopbackData(4*1200))
+	id, _ := exposer.ExposureID()
+	key, _ := exposer.Key()
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 500})
+	if err != nil {
+		t.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	surface, err := startPull(ctx, sock, addr, id, &PullOptions{Key: key})
+	if err != nil {
+		t.Fatalf("PullStart() failed: %v", err)
+	}
+	defer surface.Close()
+
+	_, _, err = PullNextInto(ctx, surface, make([]byte, 1))
+	if !errors.Is(err, ErrBufferTooSmall) {
+		t.Fatalf("Expected ErrBufferTooSmall, got %v", err)
+	}
+	var tooSmall *BufferTooSmallError
+	if !errors.As(err, &tooSmall) || tooSmall.Needed != 1200 {
+		t.Fatalf("Needed must be the chunk size 1200, got %v", err)
+	}
+
+	// Nothing was consumed: a buffer of the size asked for gets a chunk
+	n, _, err := PullNextInto(ctx, surface, make([]byte, tooSmall.Needed))
+	if err != nil || n != 1200 {
+		t.Errorf("PullNextInto with %d bytes: n=%d, err=%v", tooSmall.Needed, n, err)
+	}
+}
+
+func TestPullNextIntoClosedSurface(t *testing.T) {
+	for _, surface := range []*Surface{nil, {}} {
+		if _, _, err := PullNextInto(context.Background(), surface, make([]byte, 1500)); err == nil {
+			t.Errorf("PullNextInto(%v) must fail on a nil or closed surface", surface)
+		}
+	}
+}
+
+func TestBufferTooSmallErrorIs(t *testing.T) {
+	var err error = &BufferTooSmallError{Needed: 1200}
+	if !errors.Is(err, ErrBufferTooSmall) {
+		t.Error("BufferTooSmallError must match ErrBufferTooSmall")
+	}
+}
+
+func BenchmarkPullNextInto(b *testing.B) {
+	// One pull window of chunks: the exposer sends them all in answer to
+	// the pull request, so they wait in the socket buffer and no NAK or
+	// timeout, whose error would allocate, is needed
+	data := loopbackData(64 * 1200)
+	exposer, addr := exposeLoopback(b, nil, data)
+	id, _ := exposer.ExposureID()
+	key, err := exposer.Key()
+	if err != nil {
+		b.Fatalf("Key() failed: %v", err)
+	}
+
+	sock, err := NewSocketWithConfig(&Config{TimeoutMs: 500})
+	if err != nil {
+		b.Skip("NewSocket failed:", err)
+	}
+	defer sock.Close()
+
+	ctx := context.Background()
+	var surface *Surface
+	var received uint32
+	// Allocations while the timer is stopped, to be excluded
+	var untimed uint64
+	var m0, m1 runtime.MemStats
+	buf := make([]byte, 1500)
+	b.ReportAllocs()
+	b.SetBytes(1200)
+	runtime.ReadMemStats(&m0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Start a fresh pull whenever the previous one has every chunk;
+		// only the receive path is timed
+		if surface == nil || received == exposer.layout.ChunkCount {
+			b.StopTimer()
+			var r0, r1 runtime.MemStats
+			runtime.ReadMemStats(&r0)
+			if surface != nil {
+				surface.Close()
+				// Stay under the exposer's limit of 1000 pull requests
+				// per second from one source
+				time.Sleep(time.Millisecond)
+			}
+			if surface, err = startPull(ctx, sock, addr, id, &PullOptions{Key: key}); err != nil {
+				b.Fatalf("PullStart() failed: %v", err)
+			}
+			received = 0
+			runtime.ReadMemStats(&r1)
+			untimed += r1.Mallocs - r0.Mallocs
+			b.StartTimer()
+		}
+		if _, _, err := PullNextInto(ctx, surface, buf); err != nil {
+			b.Fatalf("PullNextInto() failed: %v", err)
+		}
+		received++
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&m1)
+	surface.Close()
+
+	// Rounded down as for the reported allocs/op, since the exposer
+	// goroutine and the runtime may allocate now and then
+	if perOp := (m1.Mallocs - m0.Mallocs - untimed) / uint64(b.N); perOp != 0 {
+		b.Fatalf("PullNextInto made %d allocs/op, want 0", perOp)
+	}
+}
+
+// ExamplePullNextInto shows how a high-frequency consumer can recycle
+// receive buffers through a sync.Pool instead of allocating per chunk.
+func ExamplePullNextInto() {
+	ctx := context.Background()
+	sock, err := NewSocket()
+	if err != nil {
+		return
+	}
+	defer sock.Close()
+
+	// The exposer's address and Exposure_ID arrive out of band
+	server := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 9000}
+	var exposureID [16]byte
+	surface, err := PullStart(ctx, sock, server, exposureID)
+	if err != nil {
+		return
+	}
+	defer surface.Close()
+
+	// Pool pointers to slices, so Put does not allocate (SA6002)
+	pool := sync.Pool{New: func() any {
+		buf := make([]byte, 1500)
+		return &buf
+	}}
+	bufp := pool.Get().(*[]byte)
+	defer pool.Put(bufp)
+
+	n, idx, err := PullNextInto(ctx, surface, *bufp)
+	var tooSmall *BufferTooSmallError
+	if errors.As(err, &tooSmall) {
+		// Grow and retry; the larger buffer goes back into the pool
+		*bufp = make([]byte, tooSmall.Needed)
+		n, idx, err = PullNextInto(ctx, surface, *bufp)
+	}
+	if err == nil {
+		fmt.Printf("chunk %d: %d bytes\n", idx, n)
+	}
+}
+
 // ── Memory ownership ─────────────────────────────────────────────────────
 
 func TestExposeDoesNotLeakOnError(t *testing.T) {
@@ -0,0 +1,107 @@
+package rgtp
+
+import (
+	"context"
+	"time"
+)
+
+// ── Exposure loop ────────────────────────────────────────────────────────
+
+// ServeOptions tunes Serve. The zero value polls with a 100ms timeout and
+// no bandwidth cap.
+type ServeOptions struct {
+	// PollTimeoutMs is passed to each Poll call (0 = 100ms).
+	PollTimeoutMs int
+
+	// MaxBytesPerSec caps the rate at which the exposer sends packets,
+	// measured as Stats.BytesSent (0 = unlimited). Up to one second's
+	// worth of bytes may be sent in a burst after an idle period.
+	MaxBytesPerSec uint64
+}
+
+// Serve polls surface until ctx is cancelled, serving pull requests as
+// they arrive. When opts.MaxBytesPerSec is set, polling is paused whenever
+// the bytes already sent exceed the cap, so over any interval Serve sends
+// at most MaxBytesPerSec per second plus the one-second burst.
+//
+// Serve always returns a non-nil error: ctx.Err() on cancellation, or the
+// first error from Poll or Stats.
+func Serve(ctx context.Context, surface *Surface, opts ServeOptions) error {
+	timeoutMs := opts.PollTimeoutMs
+	if timeoutMs <= 0 {
+		timeoutMs = 100
+	}
+
+	var bucket *tokenBucket
+	var lastSent uint64
+	if opts.MaxBytesPerSec > 0 {
+		stats, err := surface.Stats()
+		if err != nil {
+			return err
+		}
+		lastSent = stats.BytesSent
+		bucket = newTokenBucket(opts.MaxBytesPerSec, time.Now())
+	}
+
+	for {
+		if err := Poll(ctx, surface, timeoutMs); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+		}
+		if bucket == nil {
+			continue
+		}
+
+		stats, err := surface.Stats()
+		if err != nil {
+			return err
+		}
+		wait := bucket.take(stats.BytesSent-lastSent, time.Now())
+		lastSent = stats.BytesSent
+		if wait <= 0 {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// tokenBucket meters bytes against a fixed rate. The C layer sends a whole
+// poll's worth of chunks at once, so the bucket is charged after the fact
+// and may go negative; the deficit is repaid by waiting.
+type tokenBucket struct {
+	rate   float64 // bytes per second
+	burst  float64 // maximum stored tokens
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(bytesPerSec uint64, now time.Time) *tokenBucket {
+	rate := float64(bytesPerSec)
+	return &tokenBucket{rate: rate, burst: rate, tokens: rate, last: now}
+}
+
+// take charges n bytes sent at time now and returns how long to wait
+// before sending more.
+func (b *tokenBucket) take(n uint64, now time.Time) time.Duration {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
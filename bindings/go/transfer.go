@@ -0,0 +1,492 @@
+package rgtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"strings"
+)
+
+// ── Whole-exposure pulls ─────────────────────────────────────────────────
+
+// PullOptions tunes whole-exposure pulls. A nil *PullOptions selects the
+// defaults.
+type PullOptions struct {
+	// AllowPartial keeps whatever arrived when a pull stops early.
+	// Missing chunks are zero-filled so every received chunk stays at its
+	// original byte offset, and a *MissingChunksError lists the gaps.
+	// Without it any gap fails the pull and no data is kept.
+	AllowPartial bool
+
+	// MaxBytes refuses Exposures whose Manifest announces more than this
+	// many bytes, before any chunk is pulled (0 = no limit). It guards
+	// in-memory pulls against exhausting memory on huge Exposures.
+	MaxBytes uint64
+
+	// Key is the Exposure's AEAD key, obtained out of band from the
+	// exposer's Surface.Key. It is required: without it a pull fails
+	// with ErrNoKey, and with the wrong key with ErrKeyMismatch.
+	Key [32]byte
+}
+
+// ErrTooLarge is returned, wrapped, when an Exposure exceeds
+// PullOptions.MaxBytes.
+var ErrTooLarge = errors.New("rgtp: exposure exceeds size limit")
+
+// ErrNoKey is returned when a whole-exposure pull is started without
+// PullOptions.Key. Every Exposure is encrypted, so no chunk could be
+// authenticated.
+var ErrNoKey = errors.New("rgtp: no key: set PullOptions.Key to the exposer's Surface.Key")
+
+// ErrKeyMismatch is returned, wrapped, when the first chunks of a pull
+// all fail authentication: the key installed on the puller is not the
+// Exposure's.
+var ErrKeyMismatch = errors.New("rgtp: chunks fail authentication: wrong key")
+
+func (o *PullOptions) allowPartial() bool {
+	return o != nil && o.AllowPartial
+}
+
+// MissingChunksError reports the chunks that never arrived during a pull.
+// Err holds the error that stopped the pull, if any.
+type MissingChunksError struct {
+	Missing    []uint32
+	ChunkCount uint32
+	Err        error
+}
+
+func (e *MissingChunksError) Error() string {
+	const maxListed = 16
+	idx := make([]string, 0, maxListed)
+	for i, m := range e.Missing {
+		if i == maxListed {
+			idx = append(idx, "...")
+			break
+		}
+		idx = append(idx, fmt.Sprint(m))
+	}
+	msg := fmt.Sprintf("rgtp: %d of %d chunks missing [%s]",
+		len(e.Missing), e.ChunkCount, strings.Join(idx, " "))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *MissingChunksError) Unwrap() error { return e.Err }
+
+// maxTimeouts is how many consecutive receive timeouts, each lasting
+// Config.TimeoutMs, a pull tolerates before giving up on missing chunks.
+const maxTimeouts = 3
+
+// keyCheckChunks is how many AEAD failures, before any chunk has
+// authenticated, Chunks takes as a wrong key rather than corruption in
+// flight. Exposures with fewer chunks are judged on all of them.
+const keyCheckChunks = 4
+
+// Chunks returns an iterator over the chunks of a puller surface, in
+// arrival order, until every chunk of the Exposure has been received.
+//
+//	for c, err := range rgtp.Chunks(ctx, surface) {
+//		if err != nil {
+//			return err
+//		}
+//		process(c.ChunkIndex, c.Data)
+//	}
+//
+// Each chunk is yielded once, in its own exactly-sized buffer. Duplicate
+// deliveries and corrupt chunks are absorbed: a chunk that fails its AEAD
+// tag or Merkle proof is never marked received, so the C puller NAKs it
+// like any other missing chunk. If the first chunks all fail their AEAD
+// tag, the key is wrong and an error wrapping ErrKeyMismatch is yielded
+// at once. When nothing arrives for maxTimeouts receive timeouts in a
+// row, a *MissingChunksError wrapping the timeout is yielded. Any other error, including ctx's, is yielded once as the
+// second value and ends the iteration. Breaking out of the loop stops
+// pulling.
+func Chunks(ctx context.Context, surface *Surface) iter.Seq2[ChunkResult, error] {
+	return func(yield func(ChunkResult, error) bool) {
+		if surface == nil || surface.ptr == nil {
+			yield(ChunkResult{}, errors.New("surface is closed"))
+			return
+		}
+		if surface.exposer {
+			yield(ChunkResult{}, errors.New("surface is an exposer, not a puller"))
+			return
+		}
+
+		layout, err := surface.Layout()
+		if err != nil {
+			yield(ChunkResult{}, err)
+			return
+		}
+
+		// One receive buffer is reused; each chunk is copied out at its
+		// exact size so callers may keep it without pinning the buffer.
+		buf := make([]byte, layout.ChunkSize)
+		// Chunks held from an earlier pull count as received
+		got := make([]bool, layout.ChunkCount)
+		copy(got, surface.held)
+		received := uint32(0)
+		for _, ok := range got {
+			if ok {
+				received++
+			}
+		}
+		authenticated := false
+		authFailures := uint32(0)
+		timeouts := 0
+		for received < layout.ChunkCount {
+			n, idx, err := PullNextInto(ctx, surface, buf)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					yield(ChunkResult{}, ctxErr)
+					return
+				}
+				if errCode(err) == ErrCodeTimeout {
+					if timeouts++; timeouts >= maxTimeouts {
+						yield(ChunkResult{}, &MissingChunksError{
+							Missing:    missingChunks(got),
+							ChunkCount: layout.ChunkCount,
+							Err:        err,
+						})
+						return
+					}
+					continue
+				}
+				if errCode(err) == ErrCodeAuthFail && !authenticated {
+					authFailures++
+					if authFailures >= min(keyCheckChunks, layout.ChunkCount) {
+						yield(ChunkResult{}, fmt.Errorf("%w: first %d chunks rejected: %w",
+							ErrKeyMismatch, authFailures, err))
+						return
+					}
+				}
+				if isRetryable(err) {
+					continue
+				}
+				yield(ChunkResult{}, err)
+				return
+			}
+			timeouts = 0
+			authenticated = true
+			if idx < uint32(len(got)) && !got[idx] {
+				got[idx] = true
+				received++
+			}
+			c := ChunkResult{Data: append([]byte(nil), buf[:n]...), ChunkIndex: idx}
+			if !yield(c, nil) {
+				return
+			}
+		}
+	}
+}
+
+// missingChunks lists, in ascending order, the indices not yet set in got.
+func missingChunks(got []bool) []uint32 {
+	var missing []uint32
+	for idx, ok := range got {
+		if !ok {
+			missing = append(missing, uint32(idx))
+		}
+	}
+	return missing
+}
+
+// pullChunks receives chunks from a puller surface until every chunk of
+// the Exposure has arrived. Chunks are returned keyed by chunk index.
+// On error the chunks received so far are returned alongside it.
+func pullChunks(ctx context.Context, surface *Surface) (map[uint32][]byte, error) {
+	chunks := make(map[uint32][]byte)
+	for c, err := range Chunks(ctx, surface) {
+		if err != nil {
+			return chunks, err
+		}
+		chunks[c.ChunkIndex] = c.Data
+	}
+	return chunks, nil
+}
+
+// isRetryable reports whether a PullNext error on a live puller surface
+// leaves the transfer recoverable: nothing arrived yet, a duplicate or
+// stray packet was dropped, or a corrupt chunk was discarded and will
+// be re-requested. Corrupt chunks are counted in Stats.CorruptChunks.
+// Timeouts are retryable only up to the limit Chunks enforces.
+func isRetryable(err error) bool {
+	switch errCode(err) {
+	case ErrCodeTimeout, ErrCodeDropped, ErrCodeAuthFail, ErrCodeMerkleFail:
+		return true
+	}
+	return false
+}
+
+// checkChunks verifies that chunks covers every index in layout and that
+// each chunk fits its slot. Gaps are reported as a *MissingChunksError
+// listing the missing indices in ascending order.
+func checkChunks(layout Layout, chunks map[uint32][]byte) error {
+	var missing []uint32
+	for idx := uint32(0); idx < layout.ChunkCount; idx++ {
+		data, ok := chunks[idx]
+		if !ok {
+			missing = append(missing, idx)
+			continue
+		}
+		end := uint64(idx)*uint64(layout.ChunkSize) + uint64(len(data))
+		if len(data) > int(layout.ChunkSize) || end > layout.TotalSize {
+			return fmt.Errorf("rgtp: chunk %d (%d bytes) overruns the layout", idx, len(data))
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingChunksError{Missing: missing, ChunkCount: layout.ChunkCount}
+	}
+	return nil
+}
+
+// assemble copies chunks into a buffer of layout.TotalSize, each at its
+// own offset. Gaps are left zero-filled.
+func assemble(layout Layout, chunks map[uint32][]byte) []byte {
+	buf := make([]byte, layout.TotalSize)
+	for idx, data := range chunks {
+		copy(buf[uint64(idx)*uint64(layout.ChunkSize):], data)
+	}
+	return buf
+}
+
+// collectChunks runs a whole-exposure pull on surface and checks the
+// result against the surface's layout. This is the single verification
+// path shared by every whole-exposure pull.
+//
+// On success every chunk is present. With opts.AllowPartial an
+// incomplete pull returns the chunks that did arrive together with a
+// *MissingChunksError; in every other failure the chunks are nil.
+func collectChunks(ctx context.Context, surface *Surface,
+	opts *PullOptions) (Layout, map[uint32][]byte, error) {
+
+	layout, err := surface.Layout()
+	if err != nil {
+		return Layout{}, nil, err
+	}
+	if err := checkSize(layout, opts); err != nil {
+		return layout, nil, err
+	}
+
+	chunks, pullErr := pullChunks(ctx, surface)
+	if pullErr != nil && !opts.allowPartial() {
+		return layout, nil, pullErr
+	}
+	err = checkChunks(layout, chunks)
+	var missing *MissingChunksError
+	if errors.As(err, &missing) {
+		// A stalled pull already reports its gaps; keep that error
+		// rather than nesting it.
+		if !errors.As(pullErr, &missing) {
+			missing.Err = pullErr
+		}
+		if opts.allowPartial() {
+			return layout, chunks, missing
+		}
+		return layout, nil, missing
+	}
+	if err != nil {
+		return layout, nil, err
+	}
+	return layout, chunks, nil
+}
+
+// checkSize refuses layouts larger than opts.MaxBytes.
+func checkSize(layout Layout, opts *PullOptions) error {
+	if opts != nil && opts.MaxBytes > 0 && layout.TotalSize > opts.MaxBytes {
+		return fmt.Errorf("%w: %d bytes, limit %d",
+			ErrTooLarge, layout.TotalSize, opts.MaxBytes)
+	}
+	return nil
+}
+
+// startPull runs PullStart and installs opts.Key on the new surface. A
+// missing key fails with ErrNoKey before anything is sent.
+func startPull(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, opts *PullOptions) (*Surface, error) {
+
+	if opts == nil || opts.Key == ([32]byte{}) {
+		return nil, ErrNoKey
+	}
+	surface, err := PullStart(ctx, sock, server, exposureID)
+	if err != nil {
+		return nil, err
+	}
+	if err := surface.SetKey(opts.Key); err != nil {
+		surface.Close()
+		return nil, err
+	}
+	return surface, nil
+}
+
+// pullAll pulls an entire Exposure into memory and returns it with the
+// puller's final statistics. Partial results follow the collectChunks
+// contract: data is non-nil only on success or with opts.AllowPartial.
+func pullAll(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, opts *PullOptions) ([]byte, Stats, error) {
+
+	surface, err := startPull(ctx, sock, server, exposureID, opts)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	defer surface.Close()
+
+	layout, chunks, err := collectChunks(ctx, surface, opts)
+	if chunks == nil {
+		return nil, Stats{}, err
+	}
+	stats, statsErr := surface.Stats()
+	if statsErr != nil {
+		return nil, Stats{}, statsErr
+	}
+	return assemble(layout, chunks), stats, err
+}
+
+// Pull pulls an entire Exposure into memory. The data passes through the
+// same verification as PullToFile; set opts.MaxBytes to refuse Exposures
+// too large to hold in memory.
+//
+// With opts.AllowPartial an incomplete pull returns the zero-filled data
+// together with a *MissingChunksError.
+func Pull(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, opts *PullOptions) ([]byte, error) {
+
+	data, _, err := pullAll(ctx, sock, server, exposureID, opts)
+	return data, err
+}
+
+// PartialSuffix is appended to the destination path while PullToFile is
+// receiving data. Chunks are written to the partial file as they arrive.
+const PartialSuffix = ".partial"
+
+// PullToFile pulls an entire Exposure from server and writes it to path.
+//
+// Each chunk is written at its own offset in path+PartialSuffix as soon
+// as it arrives, so memory use does not grow with the Exposure and
+// reconstruction does not depend on arrival order. The file is renamed
+// to path only once every chunk has arrived, so a cancelled or failed
+// pull never leaves a truncated file at the final path.
+//
+// On failure the partial file is removed, unless opts.AllowPartial is
+// set: then the zero-filled partial file is kept, together with a
+// sidecar (PartialSuffix+StateSuffix) recording which chunks it holds,
+// and a *MissingChunksError is returned. ResumePull can then finish the
+// pull. opts.MaxBytes is honoured as for Pull.
+func PullToFile(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, path string, opts *PullOptions) error {
+
+	_, err := pullToFile(ctx, sock, server, exposureID, path, opts)
+	return err
+}
+
+// pullToFile is PullToFile, also returning the puller's final statistics.
+func pullToFile(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, path string, opts *PullOptions) (Stats, error) {
+
+	surface, err := startPull(ctx, sock, server, exposureID, opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer surface.Close()
+
+	if err := checkSize(surface.layout, opts); err != nil {
+		return Stats{}, err
+	}
+	p, err := createPartial(path, exposureID, surface.layout)
+	if err != nil {
+		return Stats{}, err
+	}
+	if err := pullIntoFile(ctx, surface, p, opts); err != nil {
+		return Stats{}, err
+	}
+	return surface.Stats()
+}
+
+// ResumePull finishes a PullToFile of the same Exposure that stopped
+// early with opts.AllowPartial, pulling only the chunks its partial file
+// lacks. Without a partial file at path it pulls from scratch. A partial
+// file left by a different Exposure fails with ErrPartialMismatch and is
+// left untouched.
+//
+// Otherwise ResumePull behaves as PullToFile, and may itself be resumed.
+func ResumePull(ctx context.Context, sock *Socket, server net.Addr,
+	exposureID [16]byte, path string, opts *PullOptions) error {
+
+	surface, err := startPull(ctx, sock, server, exposureID, opts)
+	if err != nil {
+		return err
+	}
+	defer surface.Close()
+
+	if err := checkSize(surface.layout, opts); err != nil {
+		return err
+	}
+	p, err := openPartial(path, exposureID, surface.layout)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		if p, err = createPartial(path, exposureID, surface.layout); err != nil {
+			return err
+		}
+	}
+	return pullIntoFile(ctx, surface, p, opts)
+}
+
+// pullIntoFile streams the chunks of surface that p does not yet hold
+// into p, then commits it to its destination. On failure p is removed,
+// or kept for ResumePull with opts.AllowPartial.
+func pullIntoFile(ctx context.Context, surface *Surface, p *partialFile,
+	opts *PullOptions) error {
+
+	layout := surface.layout
+	for idx, ok := range p.held {
+		if !ok {
+			continue
+		}
+		if err := surface.skipChunk(uint32(idx)); err != nil {
+			p.remove()
+			return err
+		}
+	}
+
+	var pullErr error
+	for c, err := range Chunks(ctx, surface) {
+		if err != nil {
+			pullErr = err
+			break
+		}
+		if err := p.write(layout, c.ChunkIndex, c.Data); err != nil {
+			p.remove()
+			return err
+		}
+	}
+	if pullErr == nil {
+		if err := p.commit(); err != nil {
+			p.remove()
+			return err
+		}
+		return nil
+	}
+
+	if !opts.allowPartial() {
+		p.remove()
+		return pullErr
+	}
+	if err := p.keep(); err != nil {
+		p.remove()
+		return err
+	}
+	var missing *MissingChunksError
+	if errors.As(pullErr, &missing) {
+		return pullErr
+	}
+	return &MissingChunksError{
+		Missing:    missingChunks(p.held),
+		ChunkCount: layout.ChunkCount,
+		Err:        pullErr,
+	}
+}
@@ -5,9 +5,23 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
+	"io"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"crypto/tls"
 
@@ -18,6 +32,12 @@ import (
 var (
 	certFile = flag.String("tls-cert", "cert.pem", "TLS certificate file")
 	keyFile  = flag.String("tls-key", "key.pem", "TLS key file")
+	compress = flag.Bool("compress", true, "gzip text/JSON responses when the client accepts it")
+	basePath = flag.String("base-path", os.Getenv("RED_GIANT_BASE_PATH"),
+		"URL prefix to serve under when behind a reverse proxy (e.g. /redgiant)")
+	unixSocket      = flag.String("unix", "", "listen on this Unix domain socket instead of TCP :8443")
+	shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second,
+		"how long to let in-flight downloads finish on SIGINT/SIGTERM before closing them")
 )
 
 func main() {
@@ -28,13 +48,10 @@ func main() {
 	}
 	filePath := flag.Args()[0]
 
-	// Create router with chi
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
-
-	// Serve the specific file
-	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, filePath)
+	r := newRouter(filePath, routerOptions{
+		compress: *compress,
+		basePath: *basePath,
+		config:   effectiveConfig(flag.CommandLine, filePath),
 	})
 
 	// Load TLS cert for HTTPS
@@ -44,20 +61,352 @@ func main() {
 	}
 
 	// Create HTTPS server using standard HTTP/TCP (no QUIC)
+	conns := &connTracker{active: make(map[net.Conn]struct{})}
 	server := &http.Server{
 		Addr: ":8443", // Changed from 443 to 8443 to avoid requiring admin privileges
 		TLSConfig: &tls.Config{
 			Certificates: []tls.Certificate{cert},
 			NextProtos:   []string{"http/1.1"}, // Explicitly use HTTP/1.1, not HTTP/3
 		},
-		Handler: r,
+		Handler:   r,
+		ConnState: conns.track,
 	}
 
-	log.Printf("HTTPS server running on :8443 - exposing %s", filePath)
-	log.Printf("Access from browser: https://localhost:8443/")
+	var ln net.Listener
+	if *unixSocket != "" {
+		ln, err = listenUnix(*unixSocket)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.Remove(*unixSocket)
+		log.Printf("HTTPS server running on unix:%s - exposing %s", *unixSocket, filePath)
+	} else {
+		ln, err = net.Listen("tcp", server.Addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("HTTPS server running on :8443 - exposing %s", filePath)
+		log.Printf("Access from browser: https://localhost:8443%s", strings.TrimSuffix(servePrefix(*basePath), "/")+"/")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start HTTPS server (no QUIC/HTTP3/WebTransport); certificates are
+	// already in TLSConfig
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ServeTLS(ln, "", "") }()
 
-	// Start HTTPS server (no QUIC/HTTP3/WebTransport)
-	if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil {
+	select {
+	case err := <-serveErr:
 		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process outright
+
+	log.Printf("Shutting down, waiting up to %v for in-flight requests", *shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Shutdown has already closed idle connections, so whatever is
+		// left is still mid-response
+		for _, addr := range conns.remotes() {
+			log.Printf("Force-closing connection from %s", addr)
+		}
+		server.Close()
+	}
+}
+
+// routerOptions selects the optional behaviour of newRouter.
+type routerOptions struct {
+	compress bool              // gzip compressible responses
+	basePath string            // URL prefix the file is served under
+	config   map[string]string // what /config reports
+}
+
+// publicFlags are the flags /config reports verbatim. Anything else,
+// including flags added later, is redacted until it is listed here.
+var publicFlags = map[string]bool{
+	"compress":         true,
+	"base-path":        true,
+	"unix":             true,
+	"shutdown-timeout": true,
+}
+
+// effectiveConfig returns the values of the flags in fs after flag and
+// environment defaults, with those not in publicFlags redacted.
+func effectiveConfig(fs *flag.FlagSet, filePath string) map[string]string {
+	cfg := map[string]string{"file": filePath}
+	fs.VisitAll(func(f *flag.Flag) {
+		if publicFlags[f.Name] {
+			cfg[f.Name] = f.Value.String()
+		} else {
+			cfg[f.Name] = "[redacted]"
+		}
+	})
+	return cfg
+}
+
+// newRouter builds the server's handler: the exposed file at filePath
+// under the base path, plus the /livez, /readyz and /config endpoints.
+func newRouter(filePath string, opts routerOptions) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Logger)
+	// A panicking handler gets a 500 and a logged stack trace rather than
+	// taking the whole server down
+	r.Use(middleware.Recoverer)
+	if opts.compress {
+		// Only compressible types (text, JS, JSON, ...) are encoded, so
+		// already-compressed media is passed through untouched.
+		r.Use(weakenEncodedETags)
+		r.Use(compressWholeResponses(5))
+	}
+
+	// Probes live at the root so orchestrators can reach them directly,
+	// whatever base path the proxy uses.
+	r.Get("/livez", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		// Ready only while the exposed file can actually be served
+		f, err := os.Open(filePath)
+		if err != nil {
+			http.Error(w, "file unavailable: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		f.Close()
+		w.Write([]byte("ok\n"))
+	})
+	r.Get("/config", func(w http.ResponseWriter, r *http.Request) {
+		cfg := opts.config
+		if cfg == nil {
+			cfg = map[string]string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cfg)
+	})
+
+	// Serve the specific file, under the base path if one is set
+	etags := &etagCache{path: filePath}
+	r.Route(servePrefix(opts.basePath), func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			// ServeFile answers If-None-Match with 304 once an ETag is set
+			if tag, err := etags.get(); err == nil {
+				w.Header().Set("ETag", tag)
+			}
+			http.ServeFile(w, r, filePath)
+		})
+	})
+
+	return r
+}
+
+// compressWholeResponses is middleware.Compress for complete bodies only.
+// A byte range is an offset into the identity encoding, so Range requests
+// and 206 Partial Content responses are passed through uncompressed.
+func compressWholeResponses(level int) func(http.Handler) http.Handler {
+	compress := middleware.Compress(level)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			compress(http.HandlerFunc(func(cw http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(&partialBypass{ResponseWriter: cw, plain: w}, r)
+			})).ServeHTTP(w, r)
+		})
+	}
+}
+
+// partialBypass switches a response to the uncompressed writer if its
+// status turns out to be 206.
+type partialBypass struct {
+	http.ResponseWriter // the compressing writer
+	plain               http.ResponseWriter
+}
+
+func (b *partialBypass) WriteHeader(code int) {
+	if code == http.StatusPartialContent {
+		b.ResponseWriter = b.plain
+	}
+	b.ResponseWriter.WriteHeader(code)
+}
+
+// weakenEncodedETags marks a strong ETag weak when the response below it
+// is content-encoded. The encoded body is not the bytes the strong tag
+// was computed over, so If-Range must not match it; identity responses,
+// which byte ranges are taken from, keep the strong tag.
+func weakenEncodedETags(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&etagWeakener{ResponseWriter: w, req: r}, r)
+	})
+}
+
+// etagWeakener rewrites the ETag header as the status line is written,
+// after the compressing writer beneath it has set Content-Encoding.
+type etagWeakener struct {
+	http.ResponseWriter
+	req         *http.Request
+	wroteHeader bool
+}
+
+func (e *etagWeakener) WriteHeader(code int) {
+	if !e.wroteHeader {
+		e.wroteHeader = true
+		h := e.Header()
+		tag := h.Get("ETag")
+		strong := tag != "" && !strings.HasPrefix(tag, "W/")
+		// A 304 has no body to encode; it echoes the tag form the
+		// client validated with
+		if strong && (h.Get("Content-Encoding") != "" ||
+			code == http.StatusNotModified && strings.Contains(e.req.Header.Get("If-None-Match"), "W/"+tag)) {
+			h.Set("ETag", "W/"+tag)
+		}
+	}
+	e.ResponseWriter.WriteHeader(code)
+}
+
+func (e *etagWeakener) Write(p []byte) (int, error) {
+	if !e.wroteHeader {
+		e.WriteHeader(http.StatusOK)
+	}
+	return e.ResponseWriter.Write(p)
+}
+
+func (e *etagWeakener) Flush() {
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (e *etagWeakener) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// servePrefix turns a -base-path value into the route the file is
+// served under.
+func servePrefix(basePath string) string {
+	return "/" + strings.Trim(basePath, "/")
+}
+
+// connTracker records the server's open connections so that those still
+// busy when the shutdown timeout expires can be reported.
+type connTracker struct {
+	mu     sync.Mutex
+	active map[net.Conn]struct{}
+}
+
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateNew:
+		t.active[c] = struct{}{}
+	case http.StateHijacked, http.StateClosed:
+		delete(t.active, c)
+	}
+}
+
+func (t *connTracker) remotes() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	addrs := make([]string, 0, len(t.active))
+	for c := range t.active {
+		addrs = append(addrs, c.RemoteAddr().String())
+	}
+	return addrs
+}
+
+// listenUnix listens on a Unix socket at path, first removing a socket
+// left behind by a previous run. Any other kind of file at path is left
+// alone and reported as an error. The socket is made accessible to the
+// owner and group only; it is created owner-only, so it is never open to
+// anyone else, even briefly.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, &fs.PathError{Op: "listen", Path: path, Err: fs.ErrExist}
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	var ln net.Listener
+	var err error
+	withUmask(0o077, func() { ln, err = net.Listen("unix", path) })
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// etagCache holds a strong ETag derived from the file's SHA-256 and
+// recomputes it only when the file's size or modification time changes.
+// Encoded responses carry its weak form (see weakenEncodedETags).
+type etagCache struct {
+	path string
+
+	mu      sync.Mutex
+	size    int64
+	modTime time.Time
+	tag     string
+}
+
+// errChangedWhileHashing is returned when the file changes while its tag
+// is being computed; the response then goes out without an ETag.
+var errChangedWhileHashing = errors.New("file changed while computing its ETag")
+
+func (c *etagCache) get() (string, error) {
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	if c.tag != "" && fi.Size() == c.size && fi.ModTime().Equal(c.modTime) {
+		tag := c.tag
+		c.mu.Unlock()
+		return tag, nil
+	}
+	c.mu.Unlock()
+
+	// Hash without holding the lock, so requests for an unchanged file
+	// are not held up behind a large read
+	sum, err := hashFile(c.path)
+	if err != nil {
+		return "", err
+	}
+	after, err := os.Stat(c.path)
+	if err != nil {
+		return "", err
+	}
+	if after.Size() != fi.Size() || !after.ModTime().Equal(fi.ModTime()) {
+		return "", errChangedWhileHashing
+	}
+	tag := `"` + sum + `"`
+
+	c.mu.Lock()
+	c.size, c.modTime, c.tag = fi.Size(), fi.ModTime(), tag
+	c.mu.Unlock()
+	return tag, nil
+}
+
+// hashFile returns the hex SHA-256 of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// testFile writes content to a temporary file and returns its path.
+func testFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "exposed.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// get sends a GET for target to h with the given request headers.
+func get(h http.Handler, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConditionalDownloadNotModified(t *testing.T) {
+	content := strings.Repeat("hello, red giant\n", 50)
+	r := newRouter(testFile(t, content), routerOptions{compress: true})
+
+	first := get(r, "/", nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("First download: status %d, want 200", first.Code)
+	}
+	tag := first.Header().Get("ETag")
+	if !strings.HasPrefix(tag, `"`) {
+		t.Fatalf("ETag %q of an identity response must be strong", tag)
+	}
+	gz := get(r, "/", map[string]string{"Accept-Encoding": "gzip"})
+	if gz.Header().Get("Content-Encoding") != "gzip" || gz.Header().Get("ETag") != "W/"+tag {
+		t.Fatalf("Gzipped response: Content-Encoding %q, ETag %q; want gzip, %q",
+			gz.Header().Get("Content-Encoding"), gz.Header().Get("ETag"), "W/"+tag)
+	}
+
+	for _, validator := range []string{tag, "W/" + tag} {
+		for _, enc := range []string{"", "gzip"} {
+			second := get(r, "/", map[string]string{"If-None-Match": validator, "Accept-Encoding": enc})
+			if second.Code != http.StatusNotModified {
+				t.Errorf("If-None-Match %s, Accept-Encoding %q: status %d, want 304", validator, enc, second.Code)
+			}
+			if second.Body.Len() != 0 {
+				t.Errorf("If-None-Match %s, Accept-Encoding %q: 304 carried a %d-byte body", validator, enc, second.Body.Len())
+			}
+			if got := second.Header().Get("ETag"); got != validator {
+				t.Errorf("If-None-Match %s: 304 carried ETag %q", validator, got)
+			}
+		}
+	}
+
+	if rec := get(r, "/", map[string]string{"If-None-Match": `W/"stale"`}); rec.Code != http.StatusOK {
+		t.Errorf("Stale ETag: status %d, want 200", rec.Code)
+	}
+}
+
+func TestIfRangeResumesWithStrongETag(t *testing.T) {
+	content := strings.Repeat("red giant ", 200)
+	r := newRouter(testFile(t, content), routerOptions{compress: true})
+	tag := get(r, "/", nil).Header().Get("ETag")
+
+	// A resumed download presents the tag it started with
+	rec := get(r, "/", map[string]string{"Range": "bytes=10-19", "If-Range": tag, "Accept-Encoding": "gzip"})
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != content[10:20] {
+		t.Errorf("If-Range with the strong ETag: status %d, body %q; want 206, %q",
+			rec.Code, rec.Body.String(), content[10:20])
+	}
+	// A weak tag never validates a range, so the whole file is sent
+	rec = get(r, "/", map[string]string{"Range": "bytes=10-19", "If-Range": "W/" + tag})
+	if rec.Code != http.StatusOK || rec.Body.String() != content {
+		t.Errorf("If-Range with a weak ETag: status %d, want 200 with the whole file", rec.Code)
+	}
+}
+
+func TestCompressionSkipsByteRanges(t *testing.T) {
+	content := strings.Repeat("red giant ", 200)
+	r := newRouter(testFile(t, content), routerOptions{compress: true})
+
+	if rec := get(r, "/", map[string]string{"Accept-Encoding": "gzip"}); rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Whole text download must be gzipped, got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+
+	rec := get(r, "/", map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=10-19"})
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("Range download: status %d, want 206", rec.Code)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("206 response must not be compressed, got Content-Encoding %q", enc)
+	}
+	if got := rec.Body.String(); got != content[10:20] {
+		t.Errorf("Range body = %q, want %q", got, content[10:20])
+	}
+
+	// A 206 is left alone even without a Range header
+	partial := compressWholeResponses(5)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(content))
+	}))
+	rec = get(partial, "/", map[string]string{"Accept-Encoding": "gzip"})
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" || rec.Body.String() != content {
+		t.Errorf("206 response must pass through uncompressed, got Content-Encoding %q", enc)
+	}
+}
+
+func TestRecovererTurnsPanicInto500(t *testing.T) {
+	r := newRouter(testFile(t, "x"), routerOptions{})
+	r.Get("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("handler bug")
+	})
+
+	if rec := get(r, "/boom", nil); rec.Code != http.StatusInternalServerError {
+		t.Errorf("Panicking handler: status %d, want 500", rec.Code)
+	}
+	// The server keeps serving after the panic
+	if rec := get(r, "/livez", nil); rec.Code != http.StatusOK {
+		t.Errorf("/livez after a panic: status %d, want 200", rec.Code)
+	}
+}
+
+func TestBasePathAndProbes(t *testing.T) {
+	path := testFile(t, "exposed\n")
+	r := newRouter(path, routerOptions{basePath: "/redgiant/"})
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/redgiant/", http.StatusOK, "exposed\n"},
+		{"/redgiant", http.StatusOK, "exposed\n"},
+		{"/", http.StatusNotFound, ""},
+		// Probes stay at the root whatever the base path
+		{"/livez", http.StatusOK, "ok\n"},
+		{"/readyz", http.StatusOK, "ok\n"},
+		{"/redgiant/livez", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := get(r, tt.target, nil)
+		if rec.Code != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.target, rec.Code, tt.status)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body %q, want %q", tt.target, rec.Body.String(), tt.body)
+		}
+	}
+
+	// Not ready once the exposed file is gone
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(r, "/readyz", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz without the file: status %d, want 503", rec.Code)
+	}
+	if rec := get(r, "/livez", nil); rec.Code != http.StatusOK {
+		t.Errorf("/livez without the file: status %d, want 200", rec.Code)
+	}
+}
+
+func TestConfigRedactsUnlistedFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("tls-cert", "cert.pem", "")
+	fs.String("tls-key", "key.pem", "")
+	fs.String("upstream-token", "", "")
+	fs.String("base-path", "", "")
+	if err := fs.Parse([]string{"-tls-key", "/etc/secret/key.pem", "-upstream-token", "hunter2", "-base-path", "/rg"}); err != nil {
+		t.Fatal(err)
+	}
+	r := newRouter(testFile(t, "x"), routerOptions{config: effectiveConfig(fs, "exposed.txt")})
+
+	rec := get(r, "/config", nil)
+	var cfg map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &cfg); err != nil {
+		t.Fatalf("/config body %q: %v", rec.Body.String(), err)
+	}
+	want := map[string]string{
+		"file":           "exposed.txt",
+		"base-path":      "/rg",
+		"tls-cert":       "[redacted]",
+		"tls-key":        "[redacted]",
+		"upstream-token": "[redacted]",
+	}
+	for k, v := range want {
+		if cfg[k] != v {
+			t.Errorf("/config %s = %q, want %q", k, cfg[k], v)
+		}
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") || strings.Contains(rec.Body.String(), "/etc/secret") {
+		t.Errorf("/config leaked a redacted value: %s", rec.Body.String())
+	}
+}
+
+func TestListenUnixPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix socket permissions are not enforced on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "rg.sock")
+	ln, err := listenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o660 {
+		t.Errorf("Socket mode %o, want 660", perm)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// withUmask runs fn; there is no umask to adjust on this platform.
+func withUmask(mask int, fn func()) {
+	fn()
+}
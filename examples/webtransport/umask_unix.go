@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// withUmask runs fn with the process umask set to mask. The umask is
+// process-wide, so fn should be short and run before other goroutines
+// start creating files.
+func withUmask(mask int, fn func()) {
+	old := syscall.Umask(mask)
+	defer syscall.Umask(old)
+	fn()
+}
@@ -86,6 +86,7 @@ typedef enum rgtp_error {
     RGTP_ERR_RATE_LIMITED    = -13, /**< Pull request rate limit exceeded */
     RGTP_ERR_NOT_SUPPORTED   = -14, /**< Feature not available on this platform/build */
     RGTP_ERR_INTERNAL        = -15, /**< Internal invariant violation */
+    RGTP_ERR_DROPPED         = -16, /**< Duplicate or stray packet dropped; retry */
 } rgtp_error_t;
 
 /* ═══════════════════════════════════════════════════════════════════════════
@@ -261,6 +262,19 @@ rgtp_error_t  rgtp_socket_create(const rgtp_config_t* cfg,
 /** @brief Destroy a socket and release all associated resources. */
 void          rgtp_socket_destroy(rgtp_socket_t* sock);
 
+/**
+ * @brief Retrieve the local UDP port a socket is bound to.
+ *
+ * When rgtp_config_t.port is 0 the OS picks an ephemeral port; this
+ * reports the port actually assigned. Raw-Ethernet sockets report 0.
+ *
+ * @param sock      A socket created by rgtp_socket_create().
+ * @param out_port  Receives the bound port in host byte order.
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_socket_get_port(const rgtp_socket_t* sock,
+                                    uint16_t*            out_port);
+
 /* ═══════════════════════════════════════════════════════════════════════════
  * Exposer API
  * ═══════════════════════════════════════════════════════════════════════════ */
@@ -321,6 +335,19 @@ void          rgtp_destroy_surface(rgtp_surface_t* surface);
 rgtp_error_t  rgtp_get_exposure_id(const rgtp_surface_t* surface,
                                     uint8_t out_id[16]);
 
+/**
+ * @brief Retrieve the 256-bit AEAD key that protects an Exposure.
+ *
+ * The key is not carried on the wire. The exposer hands it to each puller
+ * out-of-band, and the puller installs it with rgtp_set_key().
+ *
+ * @param surface  An exposer surface created by rgtp_expose().
+ * @param out_key  32-byte buffer to receive the key.
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_get_key(const rgtp_surface_t* surface,
+                            uint8_t out_key[32]);
+
 /* ═══════════════════════════════════════════════════════════════════════════
  * Puller API
  * ═══════════════════════════════════════════════════════════════════════════ */
@@ -344,6 +371,34 @@ rgtp_error_t  rgtp_pull_start(rgtp_socket_t*                sock,
                                const rgtp_config_t*           cfg,
                                rgtp_surface_t**               out_surface);
 
+/**
+ * @brief Install the AEAD key of the Exposure being pulled.
+ *
+ * Must be called before the first rgtp_pull_next(); chunks that arrive
+ * without the matching key fail authentication.
+ *
+ * @param surface  A puller surface created by rgtp_pull_start().
+ * @param key      32-byte key obtained from the exposer's rgtp_get_key().
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_set_key(rgtp_surface_t* surface,
+                            const uint8_t   key[32]);
+
+/**
+ * @brief Mark a chunk as already held so it is never NAKed or delivered.
+ *
+ * Resumes an interrupted pull: call it, before the first rgtp_pull_next(),
+ * for each chunk kept from an earlier pull of the same Exposure. Held
+ * chunks count towards rgtp_progress(), and retransmissions of them
+ * are dropped as duplicates.
+ *
+ * @param surface      A puller surface created by rgtp_pull_start().
+ * @param chunk_index  Index of the held chunk.
+ * @return RGTP_OK, RGTP_ERR_INVALID_ARG, or RGTP_ERR_CHUNK_INDEX_OOB.
+ */
+rgtp_error_t  rgtp_pull_skip(rgtp_surface_t* surface,
+                              uint32_t        chunk_index);
+
 /**
  * @brief Receive the next available chunk.
  *
@@ -357,7 +412,10 @@ rgtp_error_t  rgtp_pull_start(rgtp_socket_t*                sock,
  * @param out_received    Receives the number of plaintext bytes written.
  * @param out_chunk_index Receives the chunk index of the delivered chunk.
  * @return RGTP_OK, RGTP_ERR_AUTH_FAIL, RGTP_ERR_MERKLE_FAIL, RGTP_ERR_TIMEOUT,
- *         RGTP_ERR_TRUNCATED, or RGTP_ERR_CHUNK_INDEX_OOB.
+ *         RGTP_ERR_TRUNCATED, RGTP_ERR_CHUNK_INDEX_OOB, or RGTP_ERR_DROPPED
+ *         when a duplicate chunk or a packet for another Exposure was
+ *         discarded. RGTP_ERR_INVALID_ARG means the call itself was wrong,
+ *         e.g. @p buffer is too small for the chunk.
  */
 rgtp_error_t  rgtp_pull_next(rgtp_surface_t* surface,
                               void*           buffer,
@@ -373,6 +431,24 @@ rgtp_error_t  rgtp_pull_next(rgtp_surface_t* surface,
  */
 float         rgtp_progress(const rgtp_surface_t* surface);
 
+/**
+ * @brief Retrieve the chunk layout of an Exposure.
+ *
+ * For a puller surface the values are those announced in the Manifest
+ * received by rgtp_pull_start(). Every chunk except the last holds
+ * exactly @p out_chunk_size plaintext bytes.
+ *
+ * @param surface          Any surface (exposer or puller).
+ * @param out_total_size   Receives the plaintext size in bytes (may be NULL).
+ * @param out_chunk_count  Receives the number of chunks (may be NULL).
+ * @param out_chunk_size   Receives the plaintext bytes per chunk (may be NULL).
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_get_layout(const rgtp_surface_t* surface,
+                               uint64_t*             out_total_size,
+                               uint32_t*             out_chunk_count,
+                               uint32_t*             out_chunk_size);
+
 /* ═══════════════════════════════════════════════════════════════════════════
  * Statistics
  * ═══════════════════════════════════════════════════════════════════════════ */
@@ -387,6 +463,53 @@ float         rgtp_progress(const rgtp_surface_t* surface);
 rgtp_error_t  rgtp_get_stats(const rgtp_surface_t* surface,
                               rgtp_stats_t*         out);
 
+/**
+ * @brief Retrieve the number of chunks rejected by the Merkle proof check.
+ *
+ * Kept out of rgtp_stats_t so that the struct layout stays stable across
+ * releases sharing a SOVERSION.
+ *
+ * @param surface    A puller surface.
+ * @param out_count  Receives the count.
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_get_merkle_failures(const rgtp_surface_t* surface,
+                                        uint32_t*             out_count);
+
+/**
+ * @brief Retrieve a puller's pull window, in chunks.
+ *
+ * The window caps how many missing chunks one NAK requests. Congestion
+ * control halves it when the RTT doubles and grows it by one chunk per
+ * quiet RTT period, never below the floor or above 65536.
+ *
+ * @param surface      Any surface.
+ * @param out_current  Receives the current window (may be NULL).
+ * @param out_peak     Receives the largest window reached (may be NULL).
+ * @param out_floor    Receives the window's floor (may be NULL).
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_get_window(const rgtp_surface_t* surface,
+                               uint32_t*             out_current,
+                               uint32_t*             out_peak,
+                               uint32_t*             out_floor);
+
+/**
+ * @brief Override a puller's pull window.
+ *
+ * Sets the window to @p window and pins @p min_window as the floor that
+ * congestion control may shrink it to; it may still grow above @p window.
+ * For links known to sustain more than the adaptive window allows.
+ *
+ * @param surface     A puller surface.
+ * @param window      New window in chunks, at most 65536.
+ * @param min_window  New floor in chunks, from 1 to @p window.
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_set_window(rgtp_surface_t* surface,
+                               uint32_t        window,
+                               uint32_t        min_window);
+
 /**
  * @brief Retrieve latency statistics for a puller surface.
  *
@@ -397,6 +520,21 @@ rgtp_error_t  rgtp_get_stats(const rgtp_surface_t* surface,
 rgtp_error_t  rgtp_get_latency_stats(const rgtp_surface_t* surface,
                                       rgtp_latency_stats_t* out);
 
+/**
+ * @brief Retrieve an arbitrary percentile of the puller's one-way delay.
+ *
+ * Covers percentiles not carried in rgtp_latency_stats_t (e.g. p50, p90)
+ * without changing that struct's layout.
+ *
+ * @param surface  A puller surface.
+ * @param pct      Percentile in [0, 100].
+ * @param out_us   Receives the delay in microseconds; 0 before any samples.
+ * @return RGTP_OK or RGTP_ERR_INVALID_ARG.
+ */
+rgtp_error_t  rgtp_get_latency_percentile(const rgtp_surface_t* surface,
+                                           uint32_t              pct,
+                                           uint32_t*             out_us);
+
 /* ═══════════════════════════════════════════════════════════════════════════
  * Logging
  * ═══════════════════════════════════════════════════════════════════════════ */
@@ -282,3 +282,10 @@ void rgtp_socket_destroy(rgtp_socket_t *sock)
     }
     rgtp_free(sock);
 }
+
+rgtp_error_t rgtp_socket_get_port(const rgtp_socket_t *sock, uint16_t *out_port)
+{
+    if (!sock || !out_port) return RGTP_ERR_INVALID_ARG;
+    *out_port = sock->bound_port;
+    return RGTP_OK;
+}
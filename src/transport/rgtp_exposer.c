@@ -10,11 +10,12 @@
  *  5. Return fully-initialised surface (atomic: all-or-nothing).
  *
  * rgtp_poll():
- *  1. Receive pull requests via socket.
+ *  1. Receive pull requests and NAKs via socket.
  *  2. Validate via parser; check rate limiter.
  *  3. Send Manifest on first request from a new puller.
  *  4. Serve requested chunk from immutable store.
  *  5. Include Merkle proof if requested.
+ *  6. Re-serve each chunk listed in a NAK.
  *
  * Requirements: 3.1, 3.6, 3.7, 3.8, 5.2, 5.3, 5.5, 5.6, 6.1, 7.8, 21.5, 22.1, 22.2
  */
@@ -96,10 +97,23 @@ rgtp_error_t rgtp_socket_create(const rgtp_config_t* cfg, rgtp_socket_t** out)
         return RGTP_ERR_SOCKET;
     }
 
+    /* Record the port the OS assigned when cfg->port was 0 */
+    socklen_t addr_len = sizeof(addr);
+    if (getsockname(sock->fd, (struct sockaddr*)&addr, &addr_len) == 0) {
+        sock->port = ntohs(addr.sin_port);
+    }
+
     *out = sock;
     return RGTP_OK;
 }
 
+rgtp_error_t rgtp_socket_get_port(const rgtp_socket_t* sock, uint16_t* out_port)
+{
+    if (sock == NULL || out_port == NULL) return RGTP_ERR_INVALID_ARG;
+    *out_port = sock->port;
+    return RGTP_OK;
+}
+
 void rgtp_socket_destroy(rgtp_socket_t* sock)
 {
     if (!sock) return;
@@ -216,6 +230,47 @@ fail:
     return err;
 }
 
+/* ── Chunk transmission ─────────────────────────────────────────────────── */
+
+/** Send chunk @p idx to @p peer, with its Merkle proof when available. */
+static void serve_chunk(rgtp_surface_t*                s,
+                        uint32_t                       idx,
+                        bool                           want_proof,
+                        uint8_t*                       send_buf,
+                        size_t                         send_size,
+                        const struct sockaddr_storage* peer,
+                        socklen_t                      peer_len)
+{
+    if (idx >= s->chunk_count || !s->chunks[idx]) return;
+
+    rgtp_packet_t chunk_pkt;
+    size_t out_len = 0;
+
+    if (want_proof && s->merkle_proofs && s->merkle_proofs[idx]) {
+        chunk_pkt.type = RGTP_PKT_CHUNK_DATA_WITH_PROOF;
+        memcpy(chunk_pkt.chunk_with_proof.exposure_id, s->exposure_id, 16);
+        chunk_pkt.chunk_with_proof.chunk_index  = idx;
+        chunk_pkt.chunk_with_proof.proof_depth  = (uint8_t)s->proof_depth;
+        chunk_pkt.chunk_with_proof.proof        = s->merkle_proofs[idx];
+        chunk_pkt.chunk_with_proof.payload      = s->chunks[idx];
+        chunk_pkt.chunk_with_proof.payload_len  = (uint16_t)s->chunk_sizes[idx];
+    } else {
+        chunk_pkt.type = RGTP_PKT_CHUNK_DATA;
+        memcpy(chunk_pkt.chunk_data.exposure_id, s->exposure_id, 16);
+        chunk_pkt.chunk_data.chunk_index = idx;
+        chunk_pkt.chunk_data.payload     = s->chunks[idx];
+        chunk_pkt.chunk_data.payload_len = (uint16_t)s->chunk_sizes[idx];
+    }
+
+    if (rgtp_serialize_packet(&chunk_pkt, send_buf, send_size,
+                               &out_len) == RGTP_OK) {
+        sendto(s->sock->fd, (const char*)send_buf, (int)out_len, 0,
+               (const struct sockaddr*)peer, peer_len);
+        atomic_fetch_add(&s->bytes_sent, out_len);
+        atomic_fetch_add(&s->chunks_sent, 1u);
+    }
+}
+
 /* ── rgtp_poll ──────────────────────────────────────────────────────────── */
 
 rgtp_error_t rgtp_poll(rgtp_surface_t* surface, int timeout_ms)
@@ -262,12 +317,15 @@ rgtp_error_t rgtp_poll(rgtp_surface_t* surface, int timeout_ms)
         return RGTP_OK;   /* discard malformed packet, keep polling */
     }
 
-    if (pkt.type != RGTP_PKT_PULL_REQUEST) {
-        return RGTP_OK;   /* ignore non-pull packets on exposer */
+    if (pkt.type != RGTP_PKT_PULL_REQUEST && pkt.type != RGTP_PKT_NAK) {
+        return RGTP_OK;   /* ignore other packets on exposer */
     }
 
     /* Validate Exposure_ID */
-    if (memcmp(pkt.pull_request.exposure_id, surface->exposure_id, 16) != 0) {
+    const uint8_t* pkt_id = (pkt.type == RGTP_PKT_NAK)
+                            ? pkt.nak.exposure_id
+                            : pkt.pull_request.exposure_id;
+    if (memcmp(pkt_id, surface->exposure_id, 16) != 0) {
         return RGTP_OK;
     }
 
@@ -285,6 +343,19 @@ rgtp_error_t rgtp_poll(rgtp_surface_t* surface, int timeout_ms)
     /* Record pull pressure */
     rgtp_flow_record_pull(&surface->flow, now_us());
 
+    /* NAK: re-serve the listed chunks. The indices are still in wire
+     * (big-endian) order inside recv_buf. */
+    if (pkt.type == RGTP_PKT_NAK) {
+        const uint8_t* p = (const uint8_t*)pkt.nak.chunk_indices;
+        for (uint16_t i = 0; i < pkt.nak.nak_count; i++, p += 4) {
+            uint32_t idx = ((uint32_t)p[0] << 24) | ((uint32_t)p[1] << 16) |
+                           ((uint32_t)p[2] << 8)  |  (uint32_t)p[3];
+            serve_chunk(surface, idx, true, send_buf, sizeof(send_buf),
+                        &peer_addr, peer_len);
+        }
+        return RGTP_OK;
+    }
+
     /* Send Manifest first (always — stateless exposer sends it on every pull) */
     {
         rgtp_packet_t manifest_pkt;
@@ -317,38 +388,10 @@ rgtp_error_t rgtp_poll(rgtp_surface_t* surface, int timeout_ms)
         /* The pull request window_size encodes "I want the next N chunks
          * starting from the first unreceived chunk" — for a stateless exposer
          * we serve chunk indices 0..window_size-1 on the first request.
-         * In a real deployment the puller would send specific chunk indices;
-         * this simplified version serves sequentially. */
-        uint32_t idx = w;
-        if (idx >= surface->chunk_count) break;
-        if (!surface->chunks[idx]) continue;
-
-        rgtp_packet_t chunk_pkt;
-        size_t out_len = 0;
-
-        if (want_proof && surface->merkle_proofs && surface->merkle_proofs[idx]) {
-            chunk_pkt.type = RGTP_PKT_CHUNK_DATA_WITH_PROOF;
-            memcpy(chunk_pkt.chunk_with_proof.exposure_id, surface->exposure_id, 16);
-            chunk_pkt.chunk_with_proof.chunk_index  = idx;
-            chunk_pkt.chunk_with_proof.proof_depth  = (uint8_t)surface->proof_depth;
-            chunk_pkt.chunk_with_proof.proof        = surface->merkle_proofs[idx];
-            chunk_pkt.chunk_with_proof.payload      = surface->chunks[idx];
-            chunk_pkt.chunk_with_proof.payload_len  = (uint16_t)surface->chunk_sizes[idx];
-        } else {
-            chunk_pkt.type = RGTP_PKT_CHUNK_DATA;
-            memcpy(chunk_pkt.chunk_data.exposure_id, surface->exposure_id, 16);
-            chunk_pkt.chunk_data.chunk_index = idx;
-            chunk_pkt.chunk_data.payload     = surface->chunks[idx];
-            chunk_pkt.chunk_data.payload_len = (uint16_t)surface->chunk_sizes[idx];
-        }
-
-        if (rgtp_serialize_packet(&chunk_pkt, send_buf, sizeof(send_buf),
-                                   &out_len) == RGTP_OK) {
-            sendto(surface->sock->fd, (const char*)send_buf, (int)out_len, 0,
-                   (const struct sockaddr*)&peer_addr, peer_len);
-            atomic_fetch_add(&surface->bytes_sent, out_len);
-            atomic_fetch_add(&surface->chunks_sent, 1u);
-        }
+         * Later chunks, and any that were lost or corrupted, are requested
+         * by index in NAKs. */
+        serve_chunk(surface, w, want_proof, send_buf, sizeof(send_buf),
+                    &peer_addr, peer_len);
     }
 
     return RGTP_OK;
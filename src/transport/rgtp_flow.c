@@ -40,6 +40,7 @@ void rgtp_flow_init(rgtp_flow_t* f, uint32_t initial_window)
     f->window_size         = (initial_window > 0) ? initial_window : 64u;
     f->window_min          = 1u;
     f->window_max          = 65536u;
+    f->window_peak         = f->window_size;
     f->consecutive_low_rtt = 0;
     f->loss_rate           = 0.0f;
     f->fec_overhead        = 0.0f;
@@ -115,6 +116,7 @@ void rgtp_flow_on_ack(rgtp_flow_t* f)
         new_window = f->window_max;
     }
     f->window_size = new_window;
+    if (new_window > f->window_peak) f->window_peak = new_window;
 }
 
 void rgtp_flow_update_fec(rgtp_flow_t* f)
@@ -12,10 +12,12 @@
  *  2. Validate packet type BEFORE writing to buffer.
  *  3. Verify AEAD authentication tag.
  *  4. Verify Merkle proof if present.
- *  5. Check anti-replay window.
+ *  5. Check anti-replay window (authenticated chunks only).
  *  6. Update receive bitmap and sliding window.
  *  7. Update RTT EWMA.
- *  8. Issue NAK when chunk not arrived within 2x expected interval.
+ *  8. Issue NAK for the next missing chunks — including any that failed
+ *     AEAD or Merkle verification — once every requested chunk has
+ *     arrived, or when a receive times out.
  *  9. Send Rate_Report with RTT and loss_rate.
  * 10. Send Keepalive every 25 seconds.
  *
@@ -67,6 +69,76 @@ static inline int bitmap_test_bit(const uint8_t* bm, uint32_t idx)
     return (bm[idx / 8u] >> (idx % 8u)) & 1u;
 }
 
+/* ── NAK helpers ────────────────────────────────────────────────────────── */
+
+/* Indices per NAK — keeps the packet within one Ethernet MTU. */
+#define RGTP_NAK_MAX_INDICES 256u
+
+/** Ask the exposer to re-serve @p count chunk indices. */
+static void send_nak(rgtp_surface_t* s, const uint32_t* indices, uint16_t count)
+{
+    rgtp_packet_t nak;
+    nak.type = RGTP_PKT_NAK;
+    memcpy(nak.nak.exposure_id, s->exposure_id, 16);
+    nak.nak.nak_count     = count;
+    nak.nak.chunk_indices = indices;
+
+    uint8_t nak_buf[24u + RGTP_NAK_MAX_INDICES * 4u];
+    size_t  nak_len = 0;
+    if (rgtp_serialize_packet(&nak, nak_buf, sizeof(nak_buf), &nak_len) != RGTP_OK) {
+        return;
+    }
+    socklen_t peer_len = (s->peer.ss_family == AF_INET)
+                         ? sizeof(struct sockaddr_in)
+                         : sizeof(struct sockaddr_in6);
+    if (sendto(s->sock->fd, (const char*)nak_buf, (int)nak_len, 0,
+               (const struct sockaddr*)&s->peer, peer_len) >= 0) {
+        atomic_fetch_add(&s->nak_sent, 1u);
+    }
+}
+
+/**
+ * Re-request the first missing chunks, up to one pull window, and expect
+ * that many to arrive before the next batch is requested.
+ */
+static void nak_missing(rgtp_surface_t* s)
+{
+    uint32_t limit = s->flow.window_size;
+    if (limit == 0 || limit > RGTP_NAK_MAX_INDICES) limit = RGTP_NAK_MAX_INDICES;
+
+    while (s->window_base < s->chunk_count &&
+           bitmap_test_bit(s->recv_bitmap, s->window_base)) {
+        s->window_base++;
+    }
+
+    uint32_t indices[RGTP_NAK_MAX_INDICES];
+    uint32_t count = 0;
+    for (uint32_t i = s->window_base; i < s->chunk_count && count < limit; i++) {
+        if (!bitmap_test_bit(s->recv_bitmap, i)) indices[count++] = i;
+    }
+    if (count == 0) return;
+
+    send_nak(s, indices, (uint16_t)count);
+    s->outstanding = count;
+    s->nak_mark    = s->chunks_received;
+}
+
+/**
+ * Account for one requested chunk having arrived, intact or corrupt. When
+ * the whole request is in, ask for the next missing chunks — but only if
+ * the request brought something new. A request that yielded nothing, as
+ * with a wrong key, waits for the receive timeout instead of bouncing
+ * between puller and exposer.
+ */
+static void chunk_arrived(rgtp_surface_t* s)
+{
+    if (s->outstanding > 0) s->outstanding--;
+    if (s->outstanding == 0 && s->chunks_received < s->chunk_count &&
+        s->chunks_received > s->nak_mark) {
+        nak_missing(s);
+    }
+}
+
 /* ── rgtp_pull_start ────────────────────────────────────────────────────── */
 
 rgtp_error_t rgtp_pull_start(rgtp_socket_t*                sock,
@@ -82,12 +154,13 @@ rgtp_error_t rgtp_pull_start(rgtp_socket_t*                sock,
 
     int timeout_ms = cfg ? cfg->timeout_ms : 5000;
     if (timeout_ms <= 0) timeout_ms = 5000;
+    uint32_t window = (cfg && cfg->window_size) ? cfg->window_size : 64u;
 
     /* Build and send Pull_Request */
     rgtp_packet_t req;
     req.type = RGTP_PKT_PULL_REQUEST;
     memcpy(req.pull_request.exposure_id, exposure_id, 16);
-    req.pull_request.window_size   = cfg ? cfg->window_size : 64u;
+    req.pull_request.window_size   = window;
     req.pull_request.loss_rate_q16 = 0;
     req.pull_request.flags         = RGTP_PULL_FLAG_WANT_PROOF;
     req.pull_request.version_min   = RGTP_PROTOCOL_VERSION;
@@ -147,12 +220,30 @@ rgtp_error_t rgtp_pull_start(rgtp_socket_t*                sock,
     s->fec_enabled = (m->fec_n > 0 && m->fec_k > 0);
     s->fec_n       = m->fec_n;
     s->fec_k       = m->fec_k;
+    s->outstanding = (window < m->chunk_count) ? window : m->chunk_count;
     s->state       = RGTP_SURFACE_ACTIVE;
 
     *out_surface = s;
     return RGTP_OK;
 }
 
+/* ── rgtp_pull_skip ─────────────────────────────────────────────────────── */
+
+rgtp_error_t rgtp_pull_skip(rgtp_surface_t* surface, uint32_t chunk_index)
+{
+    if (surface == NULL || surface->is_exposer ||
+        surface->state == RGTP_SURFACE_DESTROYED) {
+        return RGTP_ERR_INVALID_ARG;
+    }
+    if (chunk_index >= surface->chunk_count) return RGTP_ERR_CHUNK_INDEX_OOB;
+
+    if (!bitmap_test_bit(surface->recv_bitmap, chunk_index)) {
+        bitmap_set_bit(surface->recv_bitmap, chunk_index);
+        surface->chunks_received++;
+    }
+    return RGTP_OK;
+}
+
 /* ── rgtp_pull_next ─────────────────────────────────────────────────────── */
 
 rgtp_error_t rgtp_pull_next(rgtp_surface_t* surface,
@@ -193,7 +284,11 @@ rgtp_error_t rgtp_pull_next(rgtp_surface_t* surface,
 
     ssize_t n = recvfrom(surface->sock->fd, (char*)recv_buf, sizeof(recv_buf), 0,
                           (struct sockaddr*)&from_addr, &from_len);
-    if (n <= 0) return RGTP_ERR_TIMEOUT;
+    if (n <= 0) {
+        /* Requested chunks were lost — ask for the missing ones again */
+        nak_missing(surface);
+        return RGTP_ERR_TIMEOUT;
+    }
 
     /* ── Step 1: Parse packet type BEFORE touching buffer ─────────────── */
     rgtp_packet_t pkt;
@@ -207,7 +302,7 @@ rgtp_error_t rgtp_pull_next(rgtp_surface_t* surface,
     /* Only accept chunk data packets */
     if (pkt.type != RGTP_PKT_CHUNK_DATA &&
         pkt.type != RGTP_PKT_CHUNK_DATA_WITH_PROOF) {
-        return RGTP_ERR_INVALID_ARG;
+        return RGTP_ERR_DROPPED;
     }
 
     /* Extract chunk index and encrypted payload */
@@ -231,17 +326,10 @@ rgtp_error_t rgtp_pull_next(rgtp_surface_t* surface,
 
     /* Validate Exposure_ID */
     if (memcmp(pkt.chunk_data.exposure_id, surface->exposure_id, 16) != 0) {
-        return RGTP_ERR_INVALID_ARG;
+        return RGTP_ERR_DROPPED;
     }
 
-    /* ── Step 2: Anti-replay check ─────────────────────────────────────── */
-    rgtp_replay_result_t replay = rgtp_replay_check_and_set(&surface->replay,
-                                                              chunk_index);
-    if (replay != RGTP_REPLAY_ACCEPT) {
-        return RGTP_ERR_INVALID_ARG;   /* duplicate or too old */
-    }
-
-    /* ── Step 3: AEAD decrypt — tag verified BEFORE writing to buffer ──── */
+    /* ── Step 2: AEAD decrypt — tag verified BEFORE writing to buffer ──── */
     if (ct_len < RGTP_AEAD_TAG_BYTES) {
         return RGTP_ERR_TRUNCATED;
     }
@@ -258,34 +346,54 @@ rgtp_error_t rgtp_pull_next(rgtp_surface_t* surface,
     if (err != RGTP_OK) {
         rgtp_free(pt_tmp);
         atomic_fetch_add(&surface->auth_failures, 1u);
+        chunk_arrived(surface);
         return RGTP_ERR_AUTH_FAIL;
     }
 
-    /* ── Step 4: Merkle proof verification ─────────────────────────────── */
+    /* ── Step 3: Merkle proof verification ─────────────────────────────── */
     if (proof != NULL && proof_depth > 0) {
         err = rgtp_merkle_verify(pt_tmp, pt_len,
                                   proof, proof_depth,
                                   chunk_index, surface->merkle_root);
         if (err != RGTP_OK) {
             rgtp_free(pt_tmp);
+            atomic_fetch_add(&surface->merkle_failures, 1u);
+            chunk_arrived(surface);
             return RGTP_ERR_MERKLE_FAIL;
         }
     }
 
-    /* ── Step 5: Copy plaintext to caller buffer ────────────────────────── */
+    /* The chunk must fit the caller's buffer before it is marked seen */
     if (pt_len > buf_size) {
         rgtp_free(pt_tmp);
         return RGTP_ERR_INVALID_ARG;
     }
+
+    /* ── Step 4: Anti-replay check ─────────────────────────────────────── */
+    /* Only authenticated chunks enter the window, so a corrupt or forged
+     * packet cannot block the genuine retransmission of its index. The
+     * receive bitmap is authoritative: a retransmission that arrives after
+     * the window has moved past its index is accepted if still missing. */
+    if (bitmap_test_bit(surface->recv_bitmap, chunk_index) ||
+        rgtp_replay_check_and_set(&surface->replay, chunk_index) ==
+            RGTP_REPLAY_DUPLICATE) {
+        rgtp_free(pt_tmp);
+        /* A requested chunk that is already held still answers the
+         * request; without this a resumed pull would wait out a timeout
+         * on a first window of held chunks. */
+        chunk_arrived(surface);
+        return RGTP_ERR_DROPPED;   /* duplicate */
+    }
+
+    /* ── Step 5: Copy plaintext to caller buffer ────────────────────────── */
     memcpy(buffer, pt_tmp, pt_len);
     rgtp_free(pt_tmp);
 
     /* ── Step 6: Update receive state ──────────────────────────────────── */
-    if (!bitmap_test_bit(surface->recv_bitmap, chunk_index)) {
-        bitmap_set_bit(surface->recv_bitmap, chunk_index);
-        surface->chunks_received++;
-        atomic_fetch_add(&surface->bytes_received, pt_len);
-    }
+    bitmap_set_bit(surface->recv_bitmap, chunk_index);
+    surface->chunks_received++;
+    atomic_fetch_add(&surface->bytes_received, pt_len);
+    chunk_arrived(surface);
 
     /* ── Step 7: Update RTT estimate ────────────────────────────────────── */
     uint64_t rtt_sample = pull_now_us() - t_recv_start;
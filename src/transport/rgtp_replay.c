@@ -9,7 +9,9 @@
  *   3. If bit (seq - base) set: discard (replay).
  *   4. Otherwise:              set bit, accept.
  *
- * The window base is monotonically non-decreasing.
+ * The window base is monotonically non-decreasing. Bits are indexed by
+ * seq % 256, so advancing the base only clears the evicted slots and never
+ * moves the bits of sequence numbers still inside the window.
  *
  * Requirements: 3.10, 22.6
  */
@@ -64,23 +66,21 @@ rgtp_replay_result_t rgtp_replay_check_and_set(rgtp_replay_window_t* w,
             /* Seq is so far ahead that the entire window is invalidated */
             memset(w->bitmap, 0, sizeof(w->bitmap));
             w->base = seq;
-            offset  = 0;
         } else {
             /* Clear the bits that are being evicted */
             for (uint32_t i = 0; i < advance; i++) {
-                bitmap_clear(w->bitmap, i % WINDOW_BITS);
+                bitmap_clear(w->bitmap, (w->base + i) % WINDOW_BITS);
             }
             w->base += advance;
-            offset   = seq - w->base;
         }
     }
 
     /* Duplicate / replay check */
-    if (bitmap_test(w->bitmap, offset)) {
+    if (bitmap_test(w->bitmap, seq % WINDOW_BITS)) {
         return RGTP_REPLAY_DUPLICATE;
     }
 
     /* Accept: mark as seen */
-    bitmap_set(w->bitmap, offset);
+    bitmap_set(w->bitmap, seq % WINDOW_BITS);
     return RGTP_REPLAY_ACCEPT;
 }
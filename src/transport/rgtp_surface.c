@@ -1,6 +1,7 @@
 /**
  * @file rgtp_surface.c
- * @brief Surface lifecycle: create, destroy, stats, progress, exposure ID.
+ * @brief Surface lifecycle: create, destroy, stats, progress, layout,
+ *        exposure ID, key.
  *
  * Key invariants:
  *  - rgtp_destroy_surface() is safe to call with NULL.
@@ -144,6 +145,27 @@ rgtp_error_t rgtp_get_exposure_id(const rgtp_surface_t* surface,
     return RGTP_OK;
 }
 
+/* ── Public: get_key / set_key ──────────────────────────────────────────── */
+
+rgtp_error_t rgtp_get_key(const rgtp_surface_t* surface, uint8_t out_key[32])
+{
+    if (surface == NULL || out_key == NULL || !surface->is_exposer) {
+        return RGTP_ERR_INVALID_ARG;
+    }
+    memcpy(out_key, surface->key, 32);
+    return RGTP_OK;
+}
+
+rgtp_error_t rgtp_set_key(rgtp_surface_t* surface, const uint8_t key[32])
+{
+    if (surface == NULL || key == NULL || surface->is_exposer ||
+        surface->state == RGTP_SURFACE_DESTROYED) {
+        return RGTP_ERR_INVALID_ARG;
+    }
+    memcpy(surface->key, key, 32);
+    return RGTP_OK;
+}
+
 /* ── Public: progress ───────────────────────────────────────────────────── */
 
 float rgtp_progress(const rgtp_surface_t* surface)
@@ -154,6 +176,22 @@ float rgtp_progress(const rgtp_surface_t* surface)
     return (float)surface->chunks_received / (float)surface->chunk_count;
 }
 
+/* ── Public: get_layout ─────────────────────────────────────────────────── */
+
+rgtp_error_t rgtp_get_layout(const rgtp_surface_t* surface,
+                              uint64_t*             out_total_size,
+                              uint32_t*             out_chunk_count,
+                              uint32_t*             out_chunk_size)
+{
+    if (surface == NULL) {
+        return RGTP_ERR_INVALID_ARG;
+    }
+    if (out_total_size)  *out_total_size  = surface->total_size;
+    if (out_chunk_count) *out_chunk_count = surface->chunk_count;
+    if (out_chunk_size)  *out_chunk_size  = surface->chunk_size;
+    return RGTP_OK;
+}
+
 /* ── Public: get_stats ──────────────────────────────────────────────────── */
 
 rgtp_error_t rgtp_get_stats(const rgtp_surface_t* surface, rgtp_stats_t* out)
@@ -175,6 +213,72 @@ rgtp_error_t rgtp_get_stats(const rgtp_surface_t* surface, rgtp_stats_t* out)
     return RGTP_OK;
 }
 
+rgtp_error_t rgtp_get_merkle_failures(const rgtp_surface_t* surface,
+                                       uint32_t*             out_count)
+{
+    if (surface == NULL || out_count == NULL) {
+        return RGTP_ERR_INVALID_ARG;
+    }
+    *out_count = atomic_load(&surface->merkle_failures);
+    return RGTP_OK;
+}
+
+/* ── Public: get_window / set_window ────────────────────────────────────── */
+
+rgtp_error_t rgtp_get_window(const rgtp_surface_t* surface,
+                              uint32_t*             out_current,
+                              uint32_t*             out_peak,
+                              uint32_t*             out_floor)
+{
+    if (surface == NULL) return RGTP_ERR_INVALID_ARG;
+    if (out_current) *out_current = surface->flow.window_size;
+    if (out_peak)    *out_peak    = surface->flow.window_peak;
+    if (out_floor)   *out_floor   = surface->flow.window_min;
+    return RGTP_OK;
+}
+
+rgtp_error_t rgtp_set_window(rgtp_surface_t* surface,
+                              uint32_t        window,
+                              uint32_t        min_window)
+{
+    if (surface == NULL || surface->is_exposer) return RGTP_ERR_INVALID_ARG;
+    rgtp_flow_t* f = &surface->flow;
+    if (min_window == 0 || min_window > window || window > f->window_max) {
+        return RGTP_ERR_INVALID_ARG;
+    }
+    f->window_min  = min_window;
+    f->window_size = window;
+    if (window > f->window_peak) f->window_peak = window;
+    return RGTP_OK;
+}
+
+/** Index of the @p pct-th percentile in a sorted array of @p n samples. */
+static uint32_t percentile_index(uint32_t n, uint32_t pct)
+{
+    uint32_t idx = (n * pct) / 100u;
+    return (idx >= n) ? n - 1u : idx;
+}
+
+/** Copy up to 256 latency samples into @p sorted in ascending order. */
+static uint32_t sorted_latency_samples(const rgtp_surface_t* surface,
+                                       uint32_t              sorted[256])
+{
+    uint32_t n = (surface->latency_count < 256u) ? surface->latency_count
+                                                 : 256u;
+    memcpy(sorted, surface->latency_samples, n * sizeof(uint32_t));
+    /* Simple insertion sort — n <= 256 */
+    for (uint32_t i = 1; i < n; i++) {
+        uint32_t key = sorted[i];
+        int j = (int)i - 1;
+        while (j >= 0 && sorted[j] > key) {
+            sorted[j + 1] = sorted[j];
+            j--;
+        }
+        sorted[j + 1] = key;
+    }
+    return n;
+}
+
 /* ── Public: get_latency_stats ──────────────────────────────────────────── */
 
 rgtp_error_t rgtp_get_latency_stats(const rgtp_surface_t* surface,
@@ -216,23 +320,27 @@ rgtp_error_t rgtp_get_latency_stats(const rgtp_surface_t* surface,
     }
     out->jitter_us = (uint32_t)(jitter_sum / n);
 
-    /* p99: sort a copy and pick the 99th percentile */
+    /* Percentiles: sort a copy and pick by rank */
     uint32_t sorted[256];
-    memcpy(sorted, surface->latency_samples, n * sizeof(uint32_t));
-    /* Simple insertion sort — n <= 256 */
-    for (uint32_t i = 1; i < n; i++) {
-        uint32_t key = sorted[i];
-        int j = (int)i - 1;
-        while (j >= 0 && sorted[j] > key) {
-            sorted[j + 1] = sorted[j];
-            j--;
-        }
-        sorted[j + 1] = key;
+    sorted_latency_samples(surface, sorted);
+    out->p99_us = sorted[percentile_index(n, 99u)];
+
+    return RGTP_OK;
+}
+
+/* ── Public: get_latency_percentile ─────────────────────────────────────── */
+
+rgtp_error_t rgtp_get_latency_percentile(const rgtp_surface_t* surface,
+                                          uint32_t              pct,
+                                          uint32_t*             out_us)
+{
+    if (surface == NULL || out_us == NULL || pct > 100u) {
+        return RGTP_ERR_INVALID_ARG;
     }
-    uint32_t p99_idx = (uint32_t)((n * 99u) / 100u);
-    if (p99_idx >= n) p99_idx = n - 1u;
-    out->p99_us = sorted[p99_idx];
 
+    uint32_t sorted[256];
+    uint32_t n = sorted_latency_samples(surface, sorted);
+    *out_us = (n == 0) ? 0u : sorted[percentile_index(n, pct)];
     return RGTP_OK;
 }
 
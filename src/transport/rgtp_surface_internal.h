@@ -68,6 +68,8 @@ struct rgtp_surface_s {
 
     /* ── Sliding window (puller) ────────────────────────────────────────── */
     uint32_t         window_base;         /* first unreceived chunk */
+    uint32_t         outstanding;         /* requested chunks yet to arrive */
+    uint32_t         nak_mark;            /* chunks_received at the last NAK */
 
     /* ── Anti-replay window (puller) ────────────────────────────────────── */
     rgtp_replay_window_t replay;
@@ -96,6 +98,7 @@ struct rgtp_surface_s {
     _Atomic uint32_t malformed_packets;
     _Atomic uint32_t fec_recoveries;
     _Atomic uint32_t nak_sent;
+    _Atomic uint32_t merkle_failures;
 
     /* ── Latency tracking (puller) ──────────────────────────────────────── */
     uint32_t         latency_samples[256];
@@ -117,6 +120,16 @@ struct rgtp_socket_s {
     rgtp_config_t config;
 };
 
+/* ── Surface allocation (rgtp_surface.c) ────────────────────────────────── */
+rgtp_surface_t* rgtp_surface_alloc_exposer(const rgtp_config_t* cfg,
+                                            uint32_t chunk_count,
+                                            uint32_t chunk_size,
+                                            uint64_t total_size);
+rgtp_surface_t* rgtp_surface_alloc_puller(const rgtp_config_t* cfg,
+                                           uint32_t chunk_count,
+                                           uint32_t chunk_size,
+                                           uint64_t total_size);
+
 #ifdef __cplusplus
 }
 #endif
@@ -71,6 +71,7 @@ typedef struct {
     uint32_t window_size;        /**< Current pull window size (chunks) */
     uint32_t window_min;         /**< Minimum window size (always >= 1) */
     uint32_t window_max;         /**< Maximum window size */
+    uint32_t window_peak;        /**< Largest window_size reached */
     uint32_t consecutive_low_rtt; /**< Consecutive RTT periods within 1.1x baseline */
     float    loss_rate;          /**< EWMA packet loss rate [0.0, 1.0] */
     float    fec_overhead;       /**< Current FEC parity overhead [0.0, 0.5] */
@@ -2,8 +2,9 @@
  * @file test_surface.c
  * @brief Unit tests for surface lifecycle.
  *
- * Tests: 9 cases covering null args, alloc failure, destroy, key zeroization,
- * exposure ID retrieval, and progress tracking.
+ * Tests: 12 cases covering null args, alloc failure, destroy, key zeroization,
+ * exposure ID and key retrieval, progress tracking, layout and latency
+ * reporting.
  *
  * Requirements: 17.1, 17.2
  */
@@ -154,6 +155,108 @@ static void test_get_exposure_id_null(void)
     RGTP_ASSERT_ERR(rgtp_get_exposure_id(NULL, NULL), RGTP_ERR_INVALID_ARG);
 }
 
+/* ── Test: get_layout reports the Manifest values ───────────────────────── */
+static void test_get_layout(void)
+{
+    rgtp_surface_t* s = rgtp_surface_alloc_puller(NULL, 10, 1200, 11500);
+    RGTP_ASSERT(s != NULL, "Puller surface alloc must succeed");
+
+    uint64_t total = 0;
+    uint32_t count = 0, size = 0;
+    RGTP_ASSERT_OK(rgtp_get_layout(s, &total, &count, &size));
+    RGTP_ASSERT(total == 11500, "total_size must match the Manifest");
+    RGTP_ASSERT(count == 10,    "chunk_count must match the Manifest");
+    RGTP_ASSERT(size == 1200,   "chunk_size must match the Manifest");
+
+    /* Output pointers are optional */
+    RGTP_ASSERT_OK(rgtp_get_layout(s, NULL, &count, NULL));
+    RGTP_ASSERT_ERR(rgtp_get_layout(NULL, &total, &count, &size),
+                    RGTP_ERR_INVALID_ARG);
+    rgtp_destroy_surface(s);
+}
+
+/* ── Test: get_key / set_key hand the AEAD key to a puller ─────────────── */
+static void test_key_roundtrip(void)
+{
+    rgtp_socket_t* sock = NULL;
+    RGTP_ASSERT_OK(rgtp_socket_create(NULL, &sock));
+
+    uint8_t data[64] = {0};
+    rgtp_surface_t* exposer = NULL;
+    RGTP_ASSERT_OK(rgtp_expose(sock, data, sizeof(data), NULL, &exposer));
+    rgtp_surface_t* puller = rgtp_surface_alloc_puller(NULL, 1, 1200, 64);
+    RGTP_ASSERT(puller != NULL, "Puller surface alloc must succeed");
+
+    uint8_t key[32] = {0};
+    RGTP_ASSERT_OK(rgtp_get_key(exposer, key));
+    RGTP_ASSERT_OK(rgtp_set_key(puller, key));
+    RGTP_ASSERT(memcmp(puller->key, exposer->key, 32) == 0,
+                "Puller must hold the exposer's key");
+
+    /* Only exposers hand out a key; only pullers accept one */
+    RGTP_ASSERT_ERR(rgtp_get_key(puller, key), RGTP_ERR_INVALID_ARG);
+    RGTP_ASSERT_ERR(rgtp_set_key(exposer, key), RGTP_ERR_INVALID_ARG);
+    RGTP_ASSERT_ERR(rgtp_get_key(NULL, key), RGTP_ERR_INVALID_ARG);
+
+    rgtp_destroy_surface(puller);
+    rgtp_destroy_surface(exposer);
+    rgtp_socket_destroy(sock);
+}
+
+/* ── Test: window override, floor and peak ─────────────────────────────── */
+static void test_window_override(void)
+{
+    rgtp_surface_t* s = rgtp_surface_alloc_puller(NULL, 4, 1200, 4800);
+    RGTP_ASSERT(s != NULL, "Puller surface alloc must succeed");
+
+    uint32_t cur = 0, peak = 0, floor_ = 0;
+    RGTP_ASSERT_OK(rgtp_get_window(s, &cur, &peak, &floor_));
+    RGTP_ASSERT(cur == 64u && peak == 64u && floor_ == 1u,
+                "Default window must be 64 with floor 1");
+
+    RGTP_ASSERT_OK(rgtp_set_window(s, 256u, 128u));
+    rgtp_flow_on_congestion(&s->flow);
+    rgtp_flow_on_congestion(&s->flow);
+    RGTP_ASSERT_OK(rgtp_get_window(s, &cur, &peak, &floor_));
+    RGTP_ASSERT(cur == 128u, "Congestion must not shrink the window below its floor");
+    RGTP_ASSERT(peak == 256u && floor_ == 128u, "Peak and floor must be reported");
+
+    RGTP_ASSERT_ERR(rgtp_set_window(s, 64u, 128u), RGTP_ERR_INVALID_ARG);
+    RGTP_ASSERT_ERR(rgtp_set_window(s, 64u, 0u), RGTP_ERR_INVALID_ARG);
+    RGTP_ASSERT_ERR(rgtp_set_window(s, 65537u, 1u), RGTP_ERR_INVALID_ARG);
+
+    rgtp_destroy_surface(s);
+}
+
+/* ── Test: latency mean and percentiles over a known sample set ────────── */
+static void test_latency_percentiles(void)
+{
+    rgtp_surface_t* s = rgtp_surface_alloc_puller(NULL, 4, 1200, 4800);
+    RGTP_ASSERT(s != NULL, "Puller surface alloc must succeed");
+
+    /* Samples 100..1 in reverse order: mean 50 (integer), sorted[i] = i+1 */
+    for (uint32_t i = 0; i < 100u; i++) {
+        s->latency_samples[i] = 100u - i;
+    }
+    s->latency_count = 100u;
+
+    rgtp_latency_stats_t ls;
+    RGTP_ASSERT_OK(rgtp_get_latency_stats(s, &ls));
+    RGTP_ASSERT(ls.sample_count == 100u, "sample_count must be 100");
+    RGTP_ASSERT(ls.mean_us == 50u,  "mean must equal the arithmetic mean");
+    RGTP_ASSERT(ls.min_us == 1u && ls.max_us == 100u, "min/max mismatch");
+    RGTP_ASSERT(ls.p99_us == 100u, "p99 mismatch");
+
+    uint32_t p = 0;
+    RGTP_ASSERT_OK(rgtp_get_latency_percentile(s, 50u, &p));
+    RGTP_ASSERT(p == 51u, "p50 mismatch");
+    RGTP_ASSERT_OK(rgtp_get_latency_percentile(s, 90u, &p));
+    RGTP_ASSERT(p == 91u, "p90 mismatch");
+    RGTP_ASSERT_ERR(rgtp_get_latency_percentile(s, 101u, &p),
+                    RGTP_ERR_INVALID_ARG);
+    rgtp_destroy_surface(s);
+}
+
 int main(void)
 {
     RGTP_ASSERT_OK(rgtp_init());
@@ -166,6 +269,10 @@ int main(void)
     RGTP_RUN_TEST(test_progress_zero_at_start);
     RGTP_RUN_TEST(test_progress_one_at_completion);
     RGTP_RUN_TEST(test_get_exposure_id_null);
+    RGTP_RUN_TEST(test_get_layout);
+    RGTP_RUN_TEST(test_key_roundtrip);
+    RGTP_RUN_TEST(test_window_override);
+    RGTP_RUN_TEST(test_latency_percentiles);
     RGTP_PRINT_RESULTS();
     return rgtp_test_failures > 0 ? 1 : 0;
 }